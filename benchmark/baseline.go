@@ -0,0 +1,291 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// Baseline is the aggregated result of a series of runs of one option, kept to
+// check later runs against.
+type Baseline struct {
+	Name    string                    `json:"name"`
+	Option  string                    `json:"option"`
+	Tag     string                    `json:"tag"`
+	Created time.Time                 `json:"created"`
+	Levels  map[string]*BaselineLevel `json:"levels"`
+}
+
+// BaselineLevel holds the runs at one isolation level merged into one.
+type BaselineLevel struct {
+	Runs       int                      `json:"runs"`
+	Throughput float64                  `json:"throughput"`
+	Records    stats.Summary            `json:"records"`
+	Steps      map[string]stats.Summary `json:"steps"`
+	Paths      map[string]stats.Summary `json:"paths"`
+}
+
+// NewBaseline merges runs per isolation level.
+func NewBaseline(name, option, tag string, runs []*stats.RunHistograms) *Baseline {
+	byLevel := map[string][]*stats.RunHistograms{}
+	for _, run := range runs {
+		byLevel[run.Isolation] = append(byLevel[run.Isolation], run)
+	}
+
+	b := &Baseline{Name: name, Option: option, Tag: tag, Created: time.Now().UTC(), Levels: map[string]*BaselineLevel{}}
+	for level, levelRuns := range byLevel {
+		merged := stats.MergeRuns(levelRuns)
+		l := &BaselineLevel{
+			Runs:       len(levelRuns),
+			Throughput: merged.Throughput(),
+			Records:    merged.Records.Summary(),
+			Steps:      map[string]stats.Summary{},
+			Paths:      map[string]stats.Summary{},
+		}
+		for label, h := range merged.Steps {
+			l.Steps[label] = h.Summary()
+		}
+		for path, h := range merged.Paths {
+			l.Paths[path] = h.Summary()
+		}
+		b.Levels[level] = l
+	}
+	return b
+}
+
+// SaveBaseline writes b as indented JSON to path.
+func SaveBaseline(b *Baseline, path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// LoadBaseline reads a baseline written by SaveBaseline.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return &b, nil
+}
+
+// Threshold bounds how much a metric may regress against the baseline, e.g.
+// "p99=10%" for the per-record p99, "step.insert_resource.p50=20%" for a step
+// or "path.create.p99=15%" for the records that took a path.
+type Threshold struct {
+	// Scope is "", "step" or "path", and Name the step label or path.
+	Scope  string
+	Name   string
+	Metric string
+	// MaxRegression is the allowed regression as a fraction, 0.1 for 10%.
+	MaxRegression float64
+}
+
+// DefaultThresholds are checked when no thresholds are given.
+const DefaultThresholds = "p50=10%,p99=10%,throughput=10%"
+
+var thresholdMetrics = map[string]func(stats.Summary) time.Duration{
+	"mean":   func(s stats.Summary) time.Duration { return s.Mean },
+	"p50":    func(s stats.Summary) time.Duration { return s.P50 },
+	"p90":    func(s stats.Summary) time.Duration { return s.P90 },
+	"p95":    func(s stats.Summary) time.Duration { return s.P95 },
+	"p99":    func(s stats.Summary) time.Duration { return s.P99 },
+	"p99.9":  func(s stats.Summary) time.Duration { return s.P999 },
+	"p99.99": func(s stats.Summary) time.Duration { return s.P9999 },
+	"max":    func(s stats.Summary) time.Duration { return s.Max },
+}
+
+// ParseThresholds parses a comma-separated list of thresholds.
+func ParseThresholds(spec string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := ParseThreshold(part)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+// ParseThreshold parses one threshold, see Threshold.
+func ParseThreshold(spec string) (Threshold, error) {
+	key, limit, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Threshold{}, fmt.Errorf("threshold %q: expected <metric>=<percent>%%", spec)
+	}
+	// The unit is required so that "p50=1" is not silently read as 1%.
+	number, ok := strings.CutSuffix(strings.TrimSpace(limit), "%")
+	if !ok {
+		return Threshold{}, fmt.Errorf("threshold %q: limit %q must be a percentage such as 10%%", spec, limit)
+	}
+	pct, err := strconv.ParseFloat(number, 64)
+	if err != nil || pct < 0 {
+		return Threshold{}, fmt.Errorf("threshold %q: bad percentage %q", spec, limit)
+	}
+	t := Threshold{Metric: strings.TrimSpace(key), MaxRegression: pct / 100}
+
+	for _, scope := range []string{"step", "path"} {
+		rest, ok := strings.CutPrefix(t.Metric, scope+".")
+		if !ok {
+			continue
+		}
+		// Metrics such as p99.9 contain a dot themselves, so match them as a
+		// suffix rather than splitting at the last dot.
+		for metric := range thresholdMetrics {
+			if name, ok := strings.CutSuffix(rest, "."+metric); ok && name != "" {
+				t.Scope, t.Name, t.Metric = scope, name, metric
+				break
+			}
+		}
+		if t.Scope == "" {
+			return Threshold{}, fmt.Errorf("threshold %q: expected %s.<name>.<metric>", spec, scope)
+		}
+	}
+
+	if _, known := thresholdMetrics[t.Metric]; !known && !(t.Metric == "throughput" && t.Scope == "") {
+		return Threshold{}, fmt.Errorf("threshold %q: unknown metric %q", spec, t.Metric)
+	}
+	return t, nil
+}
+
+func (t Threshold) String() string {
+	name := t.Metric
+	if t.Scope != "" {
+		name = t.Scope + "." + t.Name + "." + t.Metric
+	}
+	return fmt.Sprintf("%s=%g%%", name, t.MaxRegression*100)
+}
+
+// value returns the thresholded metric of l in milliseconds, or in records
+// per second for throughput.
+func (t Threshold) value(l *BaselineLevel) (float64, bool) {
+	if t.Metric == "throughput" {
+		return l.Throughput, true
+	}
+	summary := l.Records
+	switch t.Scope {
+	case "step":
+		s, ok := l.Steps[t.Name]
+		if !ok {
+			return 0, false
+		}
+		summary = s
+	case "path":
+		s, ok := l.Paths[t.Name]
+		if !ok {
+			return 0, false
+		}
+		summary = s
+	}
+	return float64(thresholdMetrics[t.Metric](summary)) / float64(time.Millisecond), true
+}
+
+// BaselineCheck is the outcome of one threshold at one isolation level.
+type BaselineCheck struct {
+	Level     string
+	Threshold Threshold
+	Baseline  float64
+	Current   float64
+	// Change is the relative regression; negative values are improvements.
+	Change    float64
+	Regressed bool
+	// Missing is set when the isolation level or the metric is absent from
+	// either side, so the threshold could not be checked.
+	Missing bool
+}
+
+// CheckBaseline checks current against baseline at every isolation level of
+// the baseline. Levels and metrics missing from current are reported as
+// Missing checks; it fails when the two have no isolation level in common.
+func CheckBaseline(baseline, current *Baseline, thresholds []Threshold) ([]BaselineCheck, error) {
+	var levels []string
+	common := 0
+	for level := range baseline.Levels {
+		levels = append(levels, level)
+		if _, ok := current.Levels[level]; ok {
+			common++
+		}
+	}
+	sort.Strings(levels)
+	if common == 0 {
+		return nil, fmt.Errorf("not comparable: no isolation level in common with baseline %q (baseline %s, current %s)",
+			baseline.Name, strings.Join(levels, ","), strings.Join(levelNames(current), ","))
+	}
+
+	var checks []BaselineCheck
+	for _, level := range levels {
+		for _, t := range thresholds {
+			check := BaselineCheck{Level: level, Threshold: t}
+			if _, ok := current.Levels[level]; !ok {
+				check.Missing = true
+				checks = append(checks, check)
+				continue
+			}
+			base, okBase := t.value(baseline.Levels[level])
+			cur, okCur := t.value(current.Levels[level])
+			if !okBase || !okCur || base == 0 {
+				check.Missing = true
+				checks = append(checks, check)
+				continue
+			}
+			check.Baseline, check.Current = base, cur
+			check.Change = (cur - base) / base
+			if t.Metric == "throughput" {
+				check.Change = (base - cur) / base
+			}
+			check.Regressed = check.Change > t.MaxRegression
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+func levelNames(b *Baseline) []string {
+	var names []string
+	for level := range b.Levels {
+		names = append(names, level)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintBaselineChecks writes checks as a table and returns how many regressed
+// and how many could not be checked.
+func PrintBaselineChecks(w io.Writer, baseline *Baseline, checks []BaselineCheck) (regressions, missing int) {
+	fmt.Fprintf(w, "\n🧪 Baseline %q (%s, %s, saved %s):\n", baseline.Name, baseline.Option, baseline.Tag, baseline.Created.Format(time.DateTime))
+	for _, c := range checks {
+		if c.Missing {
+			fmt.Fprintf(w, "  ⚠️ %-16s %-36s not comparable, missing on one side\n", c.Level, c.Threshold)
+			missing++
+			continue
+		}
+		mark := "✅"
+		if c.Regressed {
+			mark = "❌"
+			regressions++
+		}
+		fmt.Fprintf(w, "  %s %-16s %-36s %12.3f → %12.3f (%+.1f%% regression)\n", mark, c.Level, c.Threshold, c.Baseline, c.Current, c.Change*100)
+	}
+	return regressions, missing
+}
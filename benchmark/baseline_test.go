@@ -0,0 +1,70 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Threshold
+		wantErr bool
+	}{
+		{spec: "p99=10%", want: Threshold{Metric: "p99", MaxRegression: 0.1}},
+		{spec: "throughput=5%", want: Threshold{Metric: "throughput", MaxRegression: 0.05}},
+		{spec: "step.insert_resource.p99.9=20%", want: Threshold{Scope: "step", Name: "insert_resource", Metric: "p99.9", MaxRegression: 0.2}},
+		{spec: "path.update-both.p50=15%", want: Threshold{Scope: "path", Name: "update-both", Metric: "p50", MaxRegression: 0.15}},
+		{spec: "path.update-both.p50=1", wantErr: true},
+		{spec: "p50=-1%", wantErr: true},
+		{spec: "p42=10%", wantErr: true},
+		{spec: "step.throughput=10%", wantErr: true},
+		{spec: "p50", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseThreshold(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseThreshold(%q) = %+v, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseThreshold(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func testBaseline(isolation string, p50 time.Duration) *Baseline {
+	run := stats.NewRunHistograms(1, isolation)
+	run.RecordRecord(p50, "update", "cat1")
+	return NewBaseline("main", "option1", "test", []*stats.RunHistograms{run})
+}
+
+func TestCheckBaseline(t *testing.T) {
+	baseline := testBaseline("serializable", 10*time.Millisecond)
+	thresholds, err := ParseThresholds("p50=10%,path.update.p50=10%,path.create.p50=10%")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks, err := CheckBaseline(baseline, testBaseline("serializable", 12*time.Millisecond), thresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 3 {
+		t.Fatalf("got %d checks, want 3", len(checks))
+	}
+	if !checks[0].Regressed || !checks[1].Regressed {
+		t.Errorf("a 20%% slower p50 did not regress: %+v", checks[:2])
+	}
+	if !checks[2].Missing {
+		t.Errorf("a path missing on both sides was checked: %+v", checks[2])
+	}
+
+	if _, err := CheckBaseline(baseline, testBaseline("read_committed", 10*time.Millisecond), thresholds); err == nil {
+		t.Error("no isolation level in common, want an error")
+	}
+}
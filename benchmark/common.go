@@ -1,88 +1,87 @@
 package benchmark
 
 import (
-	"bufio"
-	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
 	"github.com/yourusername/go-db-bench/config"
-	"github.com/yourusername/go-db-bench/db/schemas/option1_denormalized_reference_2_rep_tables/models"
-	option2models "github.com/yourusername/go-db-bench/db/schemas/option2_normalized_reference_2_rep_tables/models"
-	"gorm.io/gorm"
+	"io"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 )
 
-func RunTestForOption(t *testing.T, option func(*gorm.DB, InputRecord) ([]StepTiming, error), runCount int, inputRecordsPath string, outputPerRecordCSVPath string, outputCSVPath string) {
-	startFreshCSVFile := true
-	cfg := config.LoadDBConfig()
-
-	for run := 1; run <= runCount; run++ {
-		if run > 1 {
-			startFreshCSVFile = false
-		}
-		fmt.Printf("\n🔁 Starting run %d/%d\n", run, runCount)
-
-		// 1. Load input records from file
-		fmt.Printf("\n🔁 Loading records")
-		records, err := LoadInputRecords("/Users/snehagunta/git/kessel/kessel-benchmarking/benchmark/input_files/" + inputRecordsPath)
-		if err != nil {
-			t.Fatalf("failed to load input records: %v", err)
-		}
-
-		//2. Timed: Execute the transaction, capture times for processing per record, times per SQL stmt, total time to process all records
-		durations := make([]time.Duration, 0, len(records))
-		allStepTimings := [][]StepTiming{}
-		totalElapsed, durations, allStepTimings, err := ExecuteRun(cfg, t, records, durations, allStepTimings, option)
-		if err != nil {
-			return
-		}
+// RunTestForOption is the go test adapter around Runner: record failures are
+// reported with t.Errorf and a run that cannot be carried out fails the test.
+// Results are appended as a new session to the results store at storePath;
+// use kessel-bench export to turn them into CSVs.
+func RunTestForOption(t testing.TB, optionName string, explain bool, runCount int, inputRecordsPath string, storePath string) {
+	option, err := LookupOption(optionName)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
 
-		//3. Write all record level outputs to csv
-		err = WriteCSVAllRecords(run, durations, allStepTimings, outputPerRecordCSVPath)
-		if err != nil {
-			t.Fatalf("failed to write CSV for all records: %v", err)
+	store, err := OpenResultsStore(storePath)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer store.Close()
+
+	cfg := RunnerConfig{
+		DB:        config.LoadDBConfig(),
+		Option:    option,
+		Explain:   explain,
+		RunCount:  runCount,
+		InputPath: inputRecordsPath,
+		Store:     store,
+	}
+	session := NewSession(cfg, t.Name(), []IsolationLevel{IsolationSerializable})
+	if err := session.CaptureEnvironment(cfg.DB); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := store.StartSession(session); err != nil {
+		t.Fatalf("%v", err)
+	}
+	cfg.SessionID = session.ID
+	PrintManifest(os.Stdout, session)
+	t.Logf("results session %s in %s", session.ID, storePath)
+
+	results, err := NewRunner(cfg).Run()
+	for _, result := range results {
+		for _, failure := range result.Failures {
+			t.Errorf("%v", failure)
 		}
-
-		//4. Analyze the run
-		p50, p90, p99, maxTime, maxStep := AnalyzeRun(durations, allStepTimings, run, records, totalElapsed)
-
-		// write aggregated records to csv
-		WriteCSVForRun(
-			run, totalElapsed, p50, p90, p99, maxTime, len(records), maxStep, outputCSVPath, startFreshCSVFile,
-		)
-
+	}
+	if err != nil {
+		t.Fatalf("%v", err)
 	}
 }
 
+// LoadInputRecords reads all records of the JSONL input at path. Runs stream
+// their input instead, see RecordSource.
 func LoadInputRecords(path string) ([]InputRecord, error) {
-	file, err := os.Open(path)
+	source, err := OpenRecordSource(path)
 	if err != nil {
 		return nil, err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-
-		}
-	}(file)
+	defer source.Close()
 
 	var records []InputRecord
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var rec InputRecord
-		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
-			return nil, err
+	for {
+		rec, ok := source.Next()
+		if !ok {
+			break
 		}
-		records = append(records, rec)
+		records = append(records, rec.Record)
+	}
+	if err := source.Err(); err != nil {
+		return nil, err
 	}
-	return records, scanner.Err()
+	return records, nil
 }
 
 type InputRecord struct {
@@ -95,243 +94,411 @@ type InputRecord struct {
 	ReporterVersion    string          `json:"reporter_version"`
 	Common             json.RawMessage `json:"common"`
 	Reporter           json.RawMessage `json:"reporter"`
+	// Category is the workload category the generator assigned to the
+	// record; older inputs do not have it, see Category.
+	Category string `json:"category,omitempty"`
+	// Tombstone marks a record as a tombstone. The options store the flag
+	// with the representation; they do not delete the resource.
+	Tombstone bool `json:"tombstone,omitempty"`
 }
 
 type StepTiming struct {
 	Label    string
 	SQL      string
 	Duration time.Duration
-	Explain  string
-	Vars     []interface{}
+	// Explain is the raw EXPLAIN output and Plan its parsed form; both are
+	// only set in explain mode. When explaining or parsing the plan failed,
+	// Explain starts with "❌" and the error, and Plan is nil.
+	Explain string
+	Plan    *ExplainResult
+	Vars    []interface{}
 }
 
-func GetExplainPlan(tx *gorm.DB, sql string, vars []interface{}) string {
-	explainSQL := "EXPLAIN " + sql
-	rows, err := tx.Raw(explainSQL, vars...).Rows()
-	if err != nil {
-		return fmt.Sprintf("❌ failed to get explain: %v", err)
-	}
-	defer rows.Close()
-
-	var output []string
-	for rows.Next() {
-		var line string
-		if err := rows.Scan(&line); err == nil {
-			output = append(output, line)
-		}
-	}
-	explainPlan := strings.Join(output, "\n")
-	//fmt.Printf("Explain plan calculated: %s\n", explainPlan)
-	return explainPlan
-}
-
-func WriteCSVAllRecords(
-	run int,
-	durations []time.Duration,
-	allStepTimings [][]StepTiming,
-	outputPath string,
-) error {
+// openCSVForAppend opens a CSV file for appending and reports whether it is new
+// or empty, in which case the caller writes the header first.
+func openCSVForAppend(outputPath string) (*os.File, bool, error) {
 	// Check if file exists and is empty
 	writeHeader := false
 	fileInfo, err := os.Stat(outputPath)
 	if os.IsNotExist(err) {
 		writeHeader = true
 	} else if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return nil, false, fmt.Errorf("failed to stat file: %w", err)
 	} else if fileInfo.Size() == 0 {
 		writeHeader = true
 	}
 
-	var file *os.File
-	if writeHeader {
-		file, err = os.Create(outputPath)
-	} else {
-		file, err = os.OpenFile(outputPath, os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(outputPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open CSV file: %w", err)
 	}
+	return file, writeHeader, nil
+}
+
+// WriteCSVAllRecords appends the records of result to the per-record CSV at
+// outputPath, one row per step.
+func WriteCSVAllRecords(result RunResult, outputPath string) error {
+	out, err := openRecordsCSV(outputPath)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return err
 	}
-	defer file.Close()
+	for _, rec := range result.Records {
+		if err := out.write(result, rec); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	return out.Close()
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// WriteCSVStoredRecords appends the complete records of the run at index i
+// of session to a CSV file, streaming them from the store.
+func WriteCSVStoredRecords(session *StoredSession, i int, outputPath string) error {
+	out, err := openRecordsCSV(outputPath)
+	if err != nil {
+		return err
+	}
+	err = session.EachRecord(i, func(rec RecordResult) error {
+		return out.write(session.Runs[i], rec)
+	})
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// recordsCSV appends records to a per-record CSV as they come, see
+// WriteCSVAllRecords.
+type recordsCSV struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func openRecordsCSV(outputPath string) (*recordsCSV, error) {
+	file, writeHeader, err := openCSVForAppend(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	out := &recordsCSV{file: file, writer: csv.NewWriter(file)}
 
 	if writeHeader {
-		header := []string{"run", "record_index", "record_duration_ms", "step_label", "step_duration_ms", "sql", "vars", "explain"}
-		if err := writer.Write(header); err != nil {
-			return fmt.Errorf("failed to write header: %w", err)
+		header := []string{"run", "isolation_level", "record_index", "worker", "record_duration_ms", "retries", "wasted_ms", "outcome", "path", "category", "payload_bytes", "finished_ms", "warmup", "step_label", "step_duration_ms", "sql", "vars", "explain"}
+		header = append(header, planCSVHeader...)
+		header = append(header, "error")
+		if err := out.writer.Write(header); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write header: %w", err)
 		}
 	}
+	return out, nil
+}
 
-	for i, stepTimings := range allStepTimings {
-		duration := durations[i]
-		for _, step := range stepTimings {
-			row := []string{
-				strconv.Itoa(run),
-				strconv.Itoa(i),
-				fmt.Sprintf("%.3f", duration.Seconds()*1000),
-				step.Label,
-				fmt.Sprintf("%.3f", step.Duration.Seconds()*1000),
-				step.SQL,
-				fmt.Sprintf("%v", step.Vars),
-				step.Explain,
-			}
-			if err := writer.Write(row); err != nil {
-				return fmt.Errorf("failed to write row: %w", err)
-			}
+// write appends rec, a record of result.
+func (c *recordsCSV) write(result RunResult, rec RecordResult) error {
+	steps := rec.Steps
+	if len(steps) == 0 {
+		// Keep records that failed before recording a step.
+		steps = []StepTiming{{}}
+	}
+	errMsg := ""
+	if rec.Err != nil {
+		errMsg = rec.Err.Error()
+	}
+	for _, step := range steps {
+		row := []string{
+			strconv.Itoa(result.Run),
+			string(result.Isolation),
+			strconv.Itoa(rec.Index),
+			strconv.Itoa(rec.Worker),
+			fmt.Sprintf("%.3f", rec.Duration.Seconds()*1000),
+			strconv.Itoa(rec.Retries),
+			fmt.Sprintf("%.3f", rec.WastedTime.Seconds()*1000),
+			string(rec.Outcome),
+			string(rec.Path),
+			rec.Category,
+			strconv.Itoa(rec.PayloadBytes),
+			fmt.Sprintf("%.3f", rec.Finished.Seconds()*1000),
+			strconv.FormatBool(rec.Warmup),
+			step.Label,
+			fmt.Sprintf("%.3f", step.Duration.Seconds()*1000),
+			step.SQL,
+			fmt.Sprintf("%v", step.Vars),
+			step.Explain,
+		}
+		row = append(row, planCSVColumns(step.Plan)...)
+		row = append(row, errMsg)
+		if err := c.writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
 		}
 	}
-
 	return nil
 }
 
-func AnalyzeRun(durations []time.Duration, allStepTimings [][]StepTiming, run int, records []InputRecord, totalElapsed time.Duration) (time.Duration, time.Duration, time.Duration, time.Duration, StepTiming) {
-	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
-	getPercentile := func(p float64) time.Duration {
-		index := int(float64(len(durations)) * p)
-		if index >= len(durations) {
-			index = len(durations) - 1
-		}
-		return durations[index]
+func (c *recordsCSV) Close() error {
+	c.writer.Flush()
+	err := c.writer.Error()
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
 	}
+	return err
+}
 
-	p50 := getPercentile(0.50)
-	p90 := getPercentile(0.90)
-	p99 := getPercentile(0.99)
-	maxIndex := len(durations) - 1
-	maxTime := durations[maxIndex]
-	maxTimings := allStepTimings[maxIndex]
-
-	var maxStep StepTiming
-	for _, step := range maxTimings {
-		if step.Duration > maxStep.Duration {
-			maxStep = step
-		}
-	}
+// RunSummary is the per-record latency distribution of a run.
+type RunSummary struct {
+	RecordCount int
+	// Latency is the per-record latency distribution and Steps the
+	// distribution of every step label. Both come from Histograms.
+	Latency    stats.Summary
+	Steps      []StepStats
+	Histograms *stats.RunHistograms
+	// MaxStep is the slowest step of the slowest record, the one at index
+	// MaxStepRecord.
+	MaxStep       StepTiming
+	MaxStepRecord int
+
+	// Throughput is the number of records processed per second.
+	Throughput            float64
+	SerializationFailures int
+	OtherFailures         int
+	Workers               []WorkerSummary
+
+	// Retries counts retried attempts over all records, RetriedRecords the
+	// records that needed at least one and RetriesExhausted those that still
+	// failed after the last one.
+	Retries          int
+	RetriedRecords   int
+	RetriesExhausted int
+	WastedTime       time.Duration
+}
 
-	fmt.Printf("\n📊 Run %d: Processed %d records in %s\n", run, len(records), totalElapsed)
-	fmt.Printf("⏱️ Per-record latency:\n")
-	fmt.Printf("  - p50: %s\n", p50)
-	fmt.Printf("  - p90: %s\n", p90)
-	fmt.Printf("  - p99: %s\n", p99)
-	fmt.Printf("  - maxTime: %s (%s)\n", maxTime, maxStep.Label)
-	//fmt.Printf("  - maxStep Explain:%s\n", maxStep.Explain)
-	return p50, p90, p99, maxTime, maxStep
+// WorkerSummary is the latency distribution of the records handled by one worker.
+type WorkerSummary struct {
+	Worker      int
+	RecordCount int
+	P50         time.Duration
+	P99         time.Duration
+	Max         time.Duration
 }
 
-func ExecuteRun(cfg config.DBConfig, t *testing.T, records []InputRecord, durations []time.Duration, allStepTimings [][]StepTiming, transaction func(*gorm.DB, InputRecord) ([]StepTiming, error)) (time.Duration, []time.Duration, [][]StepTiming, error) {
-	if err := config.DropAndRecreateDatabase(cfg); err != nil {
-		t.Fatalf("❌ failed to reset DB: %v", err)
+// AnalyzeRun summarizes the records of result outside its warm-up and prints
+// the summary to w.
+func AnalyzeRun(w io.Writer, result RunResult) RunSummary {
+	records := result.MeasuredRecords()
+	elapsed := result.TotalElapsed - result.Warmup.Elapsed
+	summary := RunSummary{
+		RecordCount: len(records),
+		Histograms:  stats.NewRunHistograms(result.Run, string(result.Isolation)),
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(w, "\n📊 Run %d: Processed 0 records in %s\n", result.Run, result.TotalElapsed)
+		return summary
 	}
 
-	db := config.ConnectDB()
-	sqlDB, err := db.DB()
+	summary.Histograms.Elapsed = elapsed
+	for _, rec := range records {
+		summary.Histograms.RecordRecord(rec.Duration, string(rec.Path), rec.Category)
+		summary.Histograms.RecordPayload(rec.Duration, PayloadBucket(rec.PayloadBytes))
+		for _, step := range rec.Steps {
+			summary.Histograms.RecordStep(step.Label, step.Duration)
+		}
+	}
+	summary.Latency = summary.Histograms.Records.Summary()
+	summary.Steps = StepBreakdown(summary.Histograms)
+	if elapsed > 0 {
+		summary.Throughput = float64(len(records)) / elapsed.Seconds()
+	}
 
-	defer func() {
-		err := sqlDB.Close()
-		if err != nil {
+	slowest := records[0]
+	for _, rec := range records[1:] {
+		if rec.Duration > slowest.Duration {
+			slowest = rec
+		}
+	}
+	summary.MaxStepRecord = slowest.Index
+	for _, step := range slowest.Steps {
+		if step.Duration > summary.MaxStep.Duration {
+			summary.MaxStep = step
+		}
+	}
 
+	for _, rec := range records {
+		summary.Retries += rec.Retries
+		summary.WastedTime += rec.WastedTime
+		if rec.Retries > 0 {
+			summary.RetriedRecords++
+		}
+		if rec.Outcome == OutcomeRetriesExhausted {
+			summary.RetriesExhausted++
 		}
-	}()
-
-	fmt.Printf("\n🔁 Migrating")
-	// GORM auto-migration to recreate tables
-	err = db.AutoMigrate(
-		&models.Resource{},
-		&models.CommonRepresentation{},
-		&models.ReporterRepresentation{},
-		&models.RepresentationReference{},
-		&option2models.Resource{},
-		&option2models.CommonRepresentation{},
-		&option2models.ReporterRepresentation{},
-		&option2models.RepresentationReference{},
-	)
-
-	db.Exec(`
-	CREATE UNIQUE INDEX IF NOT EXISTS unique_resource_reporter_rep_idx
-	ON representation_reference_option2 (resource_id, reporter_representation_id)
-	WHERE reporter_representation_id IS NOT NULL;`)
-
-	db.Exec(`
-	CREATE UNIQUE INDEX IF NOT EXISTS unique_resource_common_rep_idx
-	ON representation_reference_option2 (resource_id, common_representation_id)
-	WHERE common_representation_id IS NOT NULL;`)
+	}
 
-	if err != nil {
-		return 0, nil, nil, err
+	for _, failure := range result.Failures {
+		if failure.SerializationFailure() {
+			summary.SerializationFailures++
+		} else {
+			summary.OtherFailures++
+		}
 	}
 
-	startTotal := time.Now()
-	for i, rec := range records {
-		start := time.Now()
-		stepTimings, err := runInstrumentedTransaction(db, rec, transaction)
-		duration := time.Since(start)
+	if result.Concurrency > 1 {
+		perWorker := make([]*stats.Histogram, result.Concurrency)
+		for i := range perWorker {
+			perWorker[i] = stats.NewHistogram()
+		}
+		for _, rec := range records {
+			perWorker[rec.Worker].Record(rec.Duration)
+		}
+		for worker, h := range perWorker {
+			ws := h.Summary()
+			summary.Workers = append(summary.Workers, WorkerSummary{
+				Worker:      worker,
+				RecordCount: int(ws.Count),
+				P50:         ws.P50,
+				P99:         ws.P99,
+				Max:         ws.Max,
+			})
+		}
+	}
 
-		durations = append(durations, duration)
-		allStepTimings = append(allStepTimings, stepTimings)
+	fmt.Fprintf(w, "\n📊 Run %d (%s): Processed %d records in %s (%.1f records/s)\n", result.Run, result.Isolation, len(result.Records), result.TotalElapsed, summary.Throughput)
+	if result.Warmup != (Warmup{}) {
+		fmt.Fprintf(w, "🔥 %s\n", result.Warmup.String(len(records), result.TotalElapsed))
+	}
+	if len(result.Failures) > 0 {
+		fmt.Fprintf(w, "❌ %d records failed: %d serialization failures, %d other errors\n",
+			len(result.Failures), summary.SerializationFailures, summary.OtherFailures)
+	}
+	if summary.Retries > 0 {
+		fmt.Fprintf(w, "🔂 %d retries over %d records (%d exhausted), %s wasted\n",
+			summary.Retries, summary.RetriedRecords, summary.RetriesExhausted, summary.WastedTime)
+	}
+	fmt.Fprintf(w, "🐢 Slowest step of the slowest record: %s (%s)\n", summary.MaxStep.Label, summary.MaxStep.Duration)
+	PrintLatency(w, summary.Histograms)
+	if len(summary.Workers) > 0 {
+		fmt.Fprintf(w, "👷 Per-worker latency (%d workers):\n", len(summary.Workers))
+		for _, ws := range summary.Workers {
+			fmt.Fprintf(w, "  - worker %d: %d records, p50 %s, p99 %s, max %s\n", ws.Worker, ws.RecordCount, ws.P50, ws.P99, ws.Max)
+		}
+	}
+	return summary
+}
 
-		if err != nil {
-			t.Errorf("record %d transaction failed: %v", i, err)
+// restoreMaxStep fills in the SQL, vars and plan of MaxStep from rec if rec
+// is the complete record it was taken from. Runs hold only the timings of
+// their records, so AnalyzeRun finds the slowest step without them.
+func (s *RunSummary) restoreMaxStep(rec RecordResult) {
+	if rec.Index != s.MaxStepRecord || rec.Warmup {
+		return
+	}
+	for _, step := range rec.Steps {
+		if step.Label == s.MaxStep.Label && step.Duration == s.MaxStep.Duration {
+			s.MaxStep = step
+			return
 		}
 	}
-	totalElapsed := time.Since(startTotal)
-	return totalElapsed, durations, allStepTimings, err
 }
 
-func runInstrumentedTransaction(db *gorm.DB, rec InputRecord, transaction func(*gorm.DB, InputRecord) ([]StepTiming, error)) ([]StepTiming, error) {
-	//fmt.Printf("\n🔁 Running transaction")
-	var timings []StepTiming
-	var innerErr error
+// PrintLatency writes the per-record and per-step latency distributions of h.
+func PrintLatency(w io.Writer, h *stats.RunHistograms) {
+	l := h.Records.Summary()
+	fmt.Fprintf(w, "⏱️ Per-record latency (%d records):\n", l.Count)
+	fmt.Fprintf(w, "  - min: %s, mean: %s, stddev: %s\n", l.Min, l.Mean, l.StdDev)
+	fmt.Fprintf(w, "  - p50: %s, p90: %s, p95: %s\n", l.P50, l.P90, l.P95)
+	fmt.Fprintf(w, "  - p99: %s, p99.9: %s, p99.99: %s\n", l.P99, l.P999, l.P9999)
+	fmt.Fprintf(w, "  - maxTime: %s\n", l.Max)
+
+	PrintStepBreakdown(w, StepBreakdown(h))
+	printGroupLatency(w, "🔀 Per-path latency", h.Paths, stats.Keys(h.Paths))
+	printGroupLatency(w, "🗂️ Per-category latency", h.Categories, stats.Keys(h.Categories))
+	// A single bucket says nothing about how size affects latency.
+	if len(h.Payloads) > 1 {
+		printGroupLatency(w, "📦 Per-payload-size latency", h.Payloads, payloadBucketNames(h.Payloads))
+	}
+}
 
-	err := db.Transaction(func(tx *gorm.DB) error {
-		timingsResult, err := transaction(tx, rec)
-		if err != nil {
-			innerErr = err
-			return err
-		}
-		timings = timingsResult
-		return nil
-	}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+// printGroupLatency writes the distributions of histograms in the order of
+// names.
+func printGroupLatency(w io.Writer, title string, histograms map[string]*stats.Histogram, names []string) {
+	if len(histograms) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	fmt.Fprintf(w, "  %-28s %8s %12s %12s %12s %12s %12s\n", "", "count", "mean", "p50", "p90", "p99", "max")
+	for _, name := range names {
+		s := histograms[name].Summary()
+		fmt.Fprintf(w, "  %-28s %8d %12s %12s %12s %12s %12s\n", name, s.Count, s.Mean, s.P50, s.P90, s.P99, s.Max)
+	}
+}
 
-	if err != nil {
-		return timings, err
+// AnalyzeRuns prints the latency distributions of results merged into one,
+// so a series of runs can be judged as a whole.
+func AnalyzeRuns(w io.Writer, results []RunResult) *stats.RunHistograms {
+	var runs []*stats.RunHistograms
+	for _, result := range results {
+		if result.Summary.Histograms != nil {
+			runs = append(runs, result.Summary.Histograms)
+		}
 	}
-	return timings, innerErr
+	merged := stats.MergeRuns(runs)
+	fmt.Fprintf(w, "\n📊 All %d runs:\n", len(runs))
+	PrintLatency(w, merged)
+	return merged
 }
 
-func WriteCSVForRun(run int, total time.Duration, p50, p90, p99, max time.Duration, count int, maxStep StepTiming, filePath string, writeHeaders bool) {
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+func WriteCSVForRun(result RunResult, filePath string) error {
+	file, writeHeaders, err := openCSVForAppend(filePath)
 	if err != nil {
-		fmt.Printf("❌ Failed to open CSV: %v\n", err)
-		return
+		return err
 	}
 	defer file.Close()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
 	if writeHeaders {
-		writer.Write([]string{"Run no", "Timestamp", "TotalTime ms", "P50ns", "P90ns", "P99ns", "MaxTime ns", "RecordCount", "MaxStepLabel", "MaxStepSQL", "MaxStepExplainPlan"})
+		if err := writer.Write([]string{"Run no", "IsolationLevel", "Timestamp", "TotalTime ms", "P50ns", "P90ns", "P99ns", "MaxTime ns", "RecordCount", "MaxStepLabel", "MaxStepSQL", "MaxStepExplainPlan", "Concurrency", "Throughput rec/s", "SerializationFailures", "OtherFailures", "Retries", "RetriedRecords", "RetriesExhausted", "WastedTime ms", "MinTime ns", "MeanTime ns", "StdDev ns", "P95ns", "P999ns", "P9999ns", "WarmupRecords", "WarmupTime ms", "SteadyState"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
 	}
 
+	summary := result.Summary
 	record := []string{
-		fmt.Sprintf("%d", run),
+		fmt.Sprintf("%d", result.Run),
+		string(result.Isolation),
 		time.Now().Format("2006-01-02 15:04:05"),
-		fmt.Sprintf("%d", total.Milliseconds()),
-		fmt.Sprintf("%d", p50.Nanoseconds()),
-		fmt.Sprintf("%d", p90.Nanoseconds()),
-		fmt.Sprintf("%d", p99.Nanoseconds()),
-		fmt.Sprintf("%d", max.Nanoseconds()),
-		fmt.Sprintf("%d", count),
-		maxStep.Label,
-		maxStep.SQL,
-		maxStep.Explain,
-	}
-
-	err = writer.Write(record)
-	if err != nil {
-		return
+		fmt.Sprintf("%d", result.TotalElapsed.Milliseconds()),
+		fmt.Sprintf("%d", summary.Latency.P50.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.P90.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.P99.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.Max.Nanoseconds()),
+		fmt.Sprintf("%d", summary.RecordCount),
+		summary.MaxStep.Label,
+		summary.MaxStep.SQL,
+		summary.MaxStep.Explain,
+		fmt.Sprintf("%d", result.Concurrency),
+		fmt.Sprintf("%.2f", summary.Throughput),
+		fmt.Sprintf("%d", summary.SerializationFailures),
+		fmt.Sprintf("%d", summary.OtherFailures),
+		fmt.Sprintf("%d", summary.Retries),
+		fmt.Sprintf("%d", summary.RetriedRecords),
+		fmt.Sprintf("%d", summary.RetriesExhausted),
+		fmt.Sprintf("%d", summary.WastedTime.Milliseconds()),
+		fmt.Sprintf("%d", summary.Latency.Min.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.Mean.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.StdDev.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.P95.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.P999.Nanoseconds()),
+		fmt.Sprintf("%d", summary.Latency.P9999.Nanoseconds()),
+		fmt.Sprintf("%d", result.Warmup.Records),
+		fmt.Sprintf("%d", result.Warmup.Elapsed.Milliseconds()),
+		fmt.Sprintf("%t", result.Warmup.Steady),
+	}
+
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
 	}
+	writer.Flush()
+	return writer.Error()
 }
 
 func openCSVWithDateSuffix(filePath string) (*os.File, error) {
@@ -352,43 +519,3 @@ func openCSVWithDateSuffix(filePath string) (*os.File, error) {
 	}
 	return file, nil
 }
-
-func DryRunAndRecordExplainPlan(
-	tx *gorm.DB,
-	timings []StepTiming,
-	dryFunc func() *gorm.DB,
-	label string,
-) []StepTiming {
-	dry := dryFunc()
-	sql := dry.Statement.SQL.String()
-	vars := dry.Statement.Vars
-
-	explainPlan := GetExplainPlan(tx, sql, vars)
-	fmt.Println("Explain Plan")
-	fmt.Println(explainPlan)
-
-	timings = append(timings, StepTiming{
-		Label:   label,
-		SQL:     sql,
-		Vars:    vars,
-		Explain: explainPlan,
-	})
-
-	return timings
-}
-
-func ActualRunAndRecordExecutionTiming(
-	tx *gorm.DB,
-	timings []StepTiming,
-	execFunc func() (*gorm.DB, interface{}),
-	label string,
-) ([]StepTiming, error, interface{}) {
-	t0 := time.Now()
-	exec, result := execFunc()
-	err := exec.Error
-	timings = append(timings, StepTiming{
-		Label:    label,
-		Duration: time.Since(t0),
-	})
-	return timings, err, result
-}
@@ -0,0 +1,38 @@
+package benchmark
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// SQLState returns the Postgres SQLSTATE carried by err, or "" if err did not
+// come from the server.
+func SQLState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// IsSerializationFailure reports whether err is a serialization_failure raised
+// by a concurrent transaction.
+func IsSerializationFailure(err error) bool {
+	return SQLState(err) == sqlStateSerializationFailure
+}
+
+// IsRetryable reports whether a transaction that failed with err can simply be
+// run again: serialization failures and deadlocks are resolved by retrying.
+func IsRetryable(err error) bool {
+	switch SQLState(err) {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ExplainResult is one statement's EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) output.
+type ExplainResult struct {
+	Plan PlanNode `json:"Plan"`
+	// PlanningTime and ExecutionTime are in milliseconds.
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+// PlanNode is a node of a Postgres query plan. Buffer counts include the
+// node's children, as reported by Postgres.
+type PlanNode struct {
+	NodeType           string  `json:"Node Type"`
+	Operation          string  `json:"Operation"`
+	RelationName       string  `json:"Relation Name"`
+	Alias              string  `json:"Alias"`
+	IndexName          string  `json:"Index Name"`
+	JoinType           string  `json:"Join Type"`
+	ParentRelationship string  `json:"Parent Relationship"`
+	StartupCost        float64 `json:"Startup Cost"`
+	TotalCost          float64 `json:"Total Cost"`
+	PlanRows           float64 `json:"Plan Rows"`
+	PlanWidth          int     `json:"Plan Width"`
+
+	ActualStartupTime float64 `json:"Actual Startup Time"`
+	ActualTotalTime   float64 `json:"Actual Total Time"`
+	ActualRows        float64 `json:"Actual Rows"`
+	ActualLoops       float64 `json:"Actual Loops"`
+
+	SharedHitBlocks     int64 `json:"Shared Hit Blocks"`
+	SharedReadBlocks    int64 `json:"Shared Read Blocks"`
+	SharedDirtiedBlocks int64 `json:"Shared Dirtied Blocks"`
+	SharedWrittenBlocks int64 `json:"Shared Written Blocks"`
+
+	Plans []PlanNode `json:"Plans"`
+}
+
+// ParseExplainPlan parses the JSON document returned by GetExplainPlan.
+func ParseExplainPlan(raw string) (*ExplainResult, error) {
+	var results []ExplainResult
+	if err := json.Unmarshal([]byte(raw), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse explain output: %w", err)
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("expected one explained statement, got %d", len(results))
+	}
+	return &results[0], nil
+}
+
+// Walk calls fn for n and all of its descendants, depth first.
+func (n *PlanNode) Walk(fn func(*PlanNode)) {
+	fn(n)
+	for i := range n.Plans {
+		n.Plans[i].Walk(fn)
+	}
+}
+
+// PlanStats are the figures of a plan stored per step.
+type PlanStats struct {
+	EstimatedRows    float64
+	ActualRows       float64
+	SharedHitBlocks  int64
+	SharedReadBlocks int64
+	// Indexes are the distinct indexes scanned anywhere in the plan.
+	Indexes []string
+	// SeqScanRelations are the relations read by a sequential scan.
+	SeqScanRelations []string
+	PlanningTime     float64
+	ExecutionTime    float64
+}
+
+// SeqScan reports whether any relation was read sequentially.
+func (s PlanStats) SeqScan() bool {
+	return len(s.SeqScanRelations) > 0
+}
+
+// Stats summarizes the plan. Row counts are taken from the root node, which
+// is what the statement returned or modified; actual rows are multiplied by
+// the loop count because Postgres reports them per loop.
+func (r *ExplainResult) Stats() PlanStats {
+	root := r.Plan
+	stats := PlanStats{
+		EstimatedRows:    root.PlanRows,
+		ActualRows:       root.ActualRows * maxFloat(root.ActualLoops, 1),
+		SharedHitBlocks:  root.SharedHitBlocks,
+		SharedReadBlocks: root.SharedReadBlocks,
+		PlanningTime:     r.PlanningTime,
+		ExecutionTime:    r.ExecutionTime,
+	}
+
+	indexes := map[string]bool{}
+	seqScans := map[string]bool{}
+	r.Plan.Walk(func(n *PlanNode) {
+		if n.IndexName != "" {
+			indexes[n.IndexName] = true
+		}
+		if n.NodeType == "Seq Scan" {
+			seqScans[n.RelationName] = true
+		}
+	})
+	stats.Indexes = sortedKeys(indexes)
+	stats.SeqScanRelations = sortedKeys(seqScans)
+	return stats
+}
+
+// planCSVHeader and planCSVColumns are the per-step plan columns of the
+// per-record CSV. They are empty for steps without a plan.
+var planCSVHeader = []string{"plan_estimated_rows", "plan_actual_rows", "shared_hit_blocks", "shared_read_blocks", "indexes", "seq_scan", "planning_ms", "execution_ms"}
+
+func planCSVColumns(plan *ExplainResult) []string {
+	if plan == nil {
+		return make([]string, len(planCSVHeader))
+	}
+	stats := plan.Stats()
+	return []string{
+		strconv.FormatFloat(stats.EstimatedRows, 'f', -1, 64),
+		strconv.FormatFloat(stats.ActualRows, 'f', -1, 64),
+		strconv.FormatInt(stats.SharedHitBlocks, 10),
+		strconv.FormatInt(stats.SharedReadBlocks, 10),
+		strings.Join(stats.Indexes, ";"),
+		strconv.FormatBool(stats.SeqScan()),
+		fmt.Sprintf("%.3f", stats.PlanningTime),
+		fmt.Sprintf("%.3f", stats.ExecutionTime),
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+const explainSavepoint = "benchmark_explain"
+
+// GetExplainPlan returns the EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) output for
+// sql, run on the connection of tx.
+func GetExplainPlan(tx *gorm.DB, sql string, vars []interface{}) (string, error) {
+	return explainOnPool(tx.Statement.Context, tx.Statement.ConnPool, sql, vars)
+}
+
+// explainOnPool runs EXPLAIN ANALYZE for query. ANALYZE executes the
+// statement, so inside a transaction it runs in a savepoint that is rolled
+// back afterwards, and outside of one in a transaction of its own.
+func explainOnPool(ctx context.Context, pool gorm.ConnPool, query string, args []interface{}) (string, error) {
+	explainSQL := "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) " + query
+
+	var plan string
+	if beginner, ok := pool.(gorm.TxBeginner); ok {
+		tx, err := beginner.BeginTx(ctx, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to begin explain transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := tx.QueryRowContext(ctx, explainSQL, args...).Scan(&plan); err != nil {
+			return "", fmt.Errorf("failed to get explain: %w", err)
+		}
+		return plan, nil
+	}
+
+	if _, err := pool.ExecContext(ctx, "SAVEPOINT "+explainSavepoint); err != nil {
+		return "", fmt.Errorf("failed to create explain savepoint: %w", err)
+	}
+	err := pool.QueryRowContext(ctx, explainSQL, args...).Scan(&plan)
+	// Rolling back also clears the aborted state a failed EXPLAIN leaves behind.
+	if _, rbErr := pool.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+explainSavepoint); rbErr != nil {
+		return "", fmt.Errorf("failed to roll back explain savepoint: %w", rbErr)
+	}
+	if _, relErr := pool.ExecContext(ctx, "RELEASE SAVEPOINT "+explainSavepoint); relErr != nil {
+		return "", fmt.Errorf("failed to release explain savepoint: %w", relErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get explain: %w", err)
+	}
+	return plan, nil
+}
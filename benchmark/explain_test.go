@@ -0,0 +1,96 @@
+package benchmark
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExplainPlan(t *testing.T) {
+	raw, err := os.ReadFile("testdata/explain_update.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ParseExplainPlan(string(raw))
+	if err != nil {
+		t.Fatalf("ParseExplainPlan: %v", err)
+	}
+	if plan.Plan.NodeType != "ModifyTable" || plan.Plan.Operation != "Update" {
+		t.Errorf("root = %s %s, want ModifyTable Update", plan.Plan.NodeType, plan.Plan.Operation)
+	}
+	var nodes []string
+	plan.Plan.Walk(func(n *PlanNode) { nodes = append(nodes, n.NodeType) })
+	want := []string{"ModifyTable", "Nested Loop", "Seq Scan", "Index Scan", "Index Only Scan"}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("Walk visited %v, want %v", nodes, want)
+	}
+
+	stats := plan.Stats()
+	wantStats := PlanStats{
+		EstimatedRows:    0,
+		ActualRows:       0,
+		SharedHitBlocks:  14,
+		SharedReadBlocks: 2,
+		Indexes:          []string{"representation_references_pkey"},
+		SeqScanRelations: []string{"resources"},
+		PlanningTime:     0.214,
+		ExecutionTime:    0.118,
+	}
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("Stats() = %+v, want %+v", stats, wantStats)
+	}
+	if !stats.SeqScan() {
+		t.Error("SeqScan() = false, want true")
+	}
+}
+
+func TestPlanStatsMultipliesLoops(t *testing.T) {
+	plan, err := ParseExplainPlan(`[{"Plan": {"Node Type": "Index Scan", "Index Name": "resources_pkey",
+		"Plan Rows": 1, "Actual Rows": 2, "Actual Loops": 3}}]`)
+	if err != nil {
+		t.Fatalf("ParseExplainPlan: %v", err)
+	}
+	stats := plan.Stats()
+	if stats.ActualRows != 6 {
+		t.Errorf("ActualRows = %v, want 6", stats.ActualRows)
+	}
+	if stats.SeqScan() {
+		t.Error("SeqScan() = true for an index scan")
+	}
+}
+
+func TestParseExplainPlanErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, raw, want string
+	}{
+		{"not json", "Seq Scan on resources", "failed to parse explain output"},
+		{"no statement", "[]", "expected one explained statement, got 0"},
+		{"two statements", `[{"Plan": {}}, {"Plan": {}}]`, "expected one explained statement, got 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseExplainPlan(tc.raw)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ParseExplainPlan(%q) error = %v, want %q", tc.raw, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanCSVColumns(t *testing.T) {
+	if got := planCSVColumns(nil); len(got) != len(planCSVHeader) {
+		t.Errorf("planCSVColumns(nil) has %d columns, want %d", len(got), len(planCSVHeader))
+	}
+	raw, err := os.ReadFile("testdata/explain_update.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := ParseExplainPlan(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0", "0", "14", "2", "representation_references_pkey", "true", "0.214", "0.118"}
+	if got := planCSVColumns(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("planCSVColumns = %v, want %v", got, want)
+	}
+}
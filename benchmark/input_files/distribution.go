@@ -0,0 +1,200 @@
+package input_files
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Distribution decides which resource IDs the generated records refer to.
+// IDs are drawn from [0, Keys) of the distribution.
+type Distribution interface {
+	// Name identifies the distribution in the metadata of generated files.
+	Name() string
+	// Sampler returns a function drawing successive IDs. All randomness comes
+	// from r, so the same seed draws the same IDs.
+	Sampler(r *rand.Rand) func() uint64
+}
+
+// Uniform draws every ID with the same probability.
+type Uniform struct {
+	Keys uint64 `json:"keys"`
+}
+
+func NewUniform(keys uint64) (*Uniform, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("uniform: keys must be positive")
+	}
+	return &Uniform{Keys: keys}, nil
+}
+
+func (d *Uniform) Name() string { return "uniform" }
+
+func (d *Uniform) Sampler(r *rand.Rand) func() uint64 {
+	return func() uint64 { return uint64(r.Int63n(int64(d.Keys))) }
+}
+
+// Zipf draws from rand.Zipf over [0, Max] and folds the values modulo Keys,
+// as the generator always did. Max must be at least Keys-1, so every ID can
+// be drawn.
+type Zipf struct {
+	Keys uint64  `json:"keys"`
+	Max  uint64  `json:"max"`
+	S    float64 `json:"s"`
+	V    float64 `json:"v"`
+}
+
+func NewZipf(keys, max uint64, s, v float64) (*Zipf, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("zipf: keys must be positive")
+	}
+	if max < keys-1 {
+		return nil, fmt.Errorf("zipf: max %d leaves IDs %d to %d unused, need max >= keys-1", max, max+1, keys-1)
+	}
+	if s <= 1 || v < 1 {
+		return nil, fmt.Errorf("zipf: need s > 1 and v >= 1")
+	}
+	return &Zipf{Keys: keys, Max: max, S: s, V: v}, nil
+}
+
+func (d *Zipf) Name() string { return "zipf" }
+
+func (d *Zipf) Sampler(r *rand.Rand) func() uint64 {
+	zipf := rand.NewZipf(r, d.S, d.V, d.Max)
+	return func() uint64 { return zipf.Uint64() % d.Keys }
+}
+
+// Pareto draws x from a Pareto distribution with scale Xm and shape Alpha and
+// uses floor(x - Xm) as the ID. The distribution is truncated at Keys rather
+// than folded, so the tail keeps its shape.
+type Pareto struct {
+	Keys  uint64  `json:"keys"`
+	Alpha float64 `json:"alpha"`
+	Xm    float64 `json:"xm"`
+}
+
+func NewPareto(keys uint64, alpha, xm float64) (*Pareto, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("pareto: keys must be positive")
+	}
+	if !(alpha > 0 && xm > 0) || math.IsInf(alpha, 0) || math.IsInf(xm, 0) {
+		return nil, fmt.Errorf("pareto: need finite alpha > 0 and xm > 0")
+	}
+	return &Pareto{Keys: keys, Alpha: alpha, Xm: xm}, nil
+}
+
+func (d *Pareto) Name() string { return "pareto" }
+
+func (d *Pareto) Sampler(r *rand.Rand) func() uint64 {
+	// Inverting the CDF F(x) = 1 - (Xm/x)^Alpha over [0, F(Xm+Keys)) samples
+	// the truncated distribution in one draw. Redrawing instead would spin for
+	// a long time when Xm is large against Keys, as almost every draw lands
+	// beyond it. Expm1 and Log1p keep F(Xm+Keys) accurate even then.
+	limit := -math.Expm1(-d.Alpha * math.Log1p(float64(d.Keys)/d.Xm))
+	return func() uint64 {
+		u := r.Float64() * limit
+		id := d.Xm*math.Exp(-math.Log1p(-u)/d.Alpha) - d.Xm
+		if id >= float64(d.Keys) {
+			// Only rounding gets here.
+			return d.Keys - 1
+		}
+		return uint64(id)
+	}
+}
+
+// Hotspot sends HotTraffic of the draws to the first HotKeys fraction of the
+// IDs, e.g. 80% of the traffic to 20% of the keys, and the rest uniformly to
+// the others.
+type Hotspot struct {
+	Keys       uint64  `json:"keys"`
+	HotKeys    float64 `json:"hot_keys"`
+	HotTraffic float64 `json:"hot_traffic"`
+}
+
+func NewHotspot(keys uint64, hotKeys, hotTraffic float64) (*Hotspot, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("hotspot: keys must be positive")
+	}
+	if hotKeys <= 0 || hotKeys >= 1 || hotTraffic < 0 || hotTraffic > 1 {
+		return nil, fmt.Errorf("hotspot: need 0 < hot keys < 1 and 0 <= hot traffic <= 1")
+	}
+	return &Hotspot{Keys: keys, HotKeys: hotKeys, HotTraffic: hotTraffic}, nil
+}
+
+func (d *Hotspot) Name() string { return "hotspot" }
+
+func (d *Hotspot) Sampler(r *rand.Rand) func() uint64 {
+	hot := uint64(math.Ceil(d.HotKeys * float64(d.Keys)))
+	if hot >= d.Keys {
+		hot = d.Keys - 1
+	}
+	return func() uint64 {
+		if hot == 0 || r.Float64() >= d.HotTraffic {
+			return hot + uint64(r.Int63n(int64(d.Keys-hot)))
+		}
+		return uint64(r.Int63n(int64(hot)))
+	}
+}
+
+// Sequential draws Start, Start+1, ... and wraps around at Keys, so every
+// resource is created once before any is updated.
+type Sequential struct {
+	Keys  uint64 `json:"keys"`
+	Start uint64 `json:"start"`
+}
+
+func NewSequential(keys, start uint64) (*Sequential, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("sequential: keys must be positive")
+	}
+	return &Sequential{Keys: keys, Start: start % keys}, nil
+}
+
+func (d *Sequential) Name() string { return "sequential" }
+
+func (d *Sequential) Sampler(*rand.Rand) func() uint64 {
+	next := d.Start
+	return func() uint64 {
+		id := next
+		next = (next + 1) % d.Keys
+		return id
+	}
+}
+
+// Latest favours recently created resources, like YCSB's latest
+// distribution: with probability Insert the next unused ID is created,
+// otherwise an existing one is drawn with Zipf exponent S over how recently
+// it was created. Once all Keys exist, every draw is an update.
+type Latest struct {
+	Keys   uint64  `json:"keys"`
+	Insert float64 `json:"insert"`
+	S      float64 `json:"s"`
+}
+
+func NewLatest(keys uint64, insert, s float64) (*Latest, error) {
+	if keys == 0 {
+		return nil, fmt.Errorf("latest: keys must be positive")
+	}
+	if insert <= 0 || insert > 1 || s <= 1 {
+		return nil, fmt.Errorf("latest: need 0 < insert <= 1 and s > 1")
+	}
+	return &Latest{Keys: keys, Insert: insert, S: s}, nil
+}
+
+func (d *Latest) Name() string { return "latest" }
+
+func (d *Latest) Sampler(r *rand.Rand) func() uint64 {
+	zipf := rand.NewZipf(r, d.S, 1, d.Keys-1)
+	var created uint64
+	return func() uint64 {
+		if created == 0 || (created < d.Keys && r.Float64() < d.Insert) {
+			created++
+			return created - 1
+		}
+		for {
+			if age := zipf.Uint64(); age < created {
+				return created - 1 - age
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package input_files
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParetoSampler(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      uint64
+		alpha, xm float64
+	}{
+		{"heavy head", 100, 1.3, 1},
+		{"flat", 100, 0.1, 1},
+		// Nearly every untruncated draw lands beyond Keys here.
+		{"xm far above keys", 10, 0.5, 1e9},
+		{"single key", 1, 2, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewPareto(tt.keys, tt.alpha, tt.xm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := d.Sampler(rand.New(rand.NewSource(1)))
+			counts := make([]int, tt.keys)
+			const draws = 20000
+			for i := 0; i < draws; i++ {
+				id := next()
+				if id >= tt.keys {
+					t.Fatalf("drew %d, want below %d", id, tt.keys)
+				}
+				counts[id]++
+			}
+
+			// The probability of an ID is the Pareto mass of [xm+id, xm+id+1)
+			// over the mass below xm+keys.
+			cdf := func(x float64) float64 { return 1 - math.Pow(tt.xm/x, tt.alpha) }
+			total := cdf(tt.xm + float64(tt.keys))
+			for id := uint64(0); id < tt.keys && id < 5; id++ {
+				want := (cdf(tt.xm+float64(id)+1) - cdf(tt.xm+float64(id))) / total
+				got := float64(counts[id]) / draws
+				if math.Abs(got-want) > 0.02 {
+					t.Errorf("id %d drawn %.3f of the time, want %.3f", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewParetoValidates(t *testing.T) {
+	for _, args := range []struct {
+		keys      uint64
+		alpha, xm float64
+	}{
+		{0, 1, 1},
+		{10, 0, 1},
+		{10, 1, 0},
+		{10, math.NaN(), 1},
+		{10, 1, math.Inf(1)},
+	} {
+		if _, err := NewPareto(args.keys, args.alpha, args.xm); err == nil {
+			t.Errorf("NewPareto(%d, %v, %v) succeeded, want an error", args.keys, args.alpha, args.xm)
+		}
+	}
+}
+
+func TestNewZipfValidates(t *testing.T) {
+	for _, args := range []struct {
+		keys, max uint64
+		s, v      float64
+	}{
+		{0, 100, 1.3, 1},
+		{100, 0, 1.3, 1},
+		{100, 98, 1.3, 1},
+		{100, 100, 1, 1},
+		{100, 100, 1.3, 0.5},
+	} {
+		if _, err := NewZipf(args.keys, args.max, args.s, args.v); err == nil {
+			t.Errorf("NewZipf(%d, %d, %v, %v) succeeded, want an error", args.keys, args.max, args.s, args.v)
+		}
+	}
+	for _, max := range []uint64{99, 100, 1000} {
+		if _, err := NewZipf(100, max, 1.3, 1); err != nil {
+			t.Errorf("NewZipf(100, %d, 1.3, 1): %v", max, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package input_files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func generateSHA256(t *testing.T, w *Workload, dist Distribution, seed int64) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.jsonl")
+	if _, err := GenerateRecords(w, dist, 500, seed, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+
+	var meta struct {
+		SHA256 string `json:"sha256"`
+	}
+	raw, err := os.ReadFile(MetadataPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(sum[:]); meta.SHA256 != got {
+		t.Errorf("metadata sha256 %s, file has %s", meta.SHA256, got)
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+func TestGenerateRecordsDeterministic(t *testing.T) {
+	fleet, err := LoadWorkload("workloads/fleet.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	distributions := []func() (Distribution, error){
+		func() (Distribution, error) { return NewUniform(100) },
+		func() (Distribution, error) { return NewZipf(100, 100, 1.3, 1) },
+		func() (Distribution, error) { return NewPareto(100, 1.3, 1) },
+		func() (Distribution, error) { return NewHotspot(100, 0.2, 0.8) },
+		func() (Distribution, error) { return NewSequential(100, 0) },
+		func() (Distribution, error) { return NewLatest(100, 0.1, 1.3) },
+	}
+	for _, w := range []*Workload{DefaultWorkload(), fleet} {
+		for _, newDist := range distributions {
+			dist, err := newDist()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Run(w.Name+"/"+dist.Name(), func(t *testing.T) {
+				first := generateSHA256(t, w, dist, 7)
+				if second := generateSHA256(t, w, dist, 7); second != first {
+					t.Errorf("same seed produced %s and %s", first, second)
+				}
+				if other := generateSHA256(t, w, dist, 8); other == first {
+					t.Errorf("seeds 7 and 8 produced the same file %s", first)
+				}
+			})
+		}
+	}
+}
+
+func TestSizeCounterSummary(t *testing.T) {
+	c := newSizeCounter()
+	if c.summary() != nil {
+		t.Error("summary of no payloads is not nil")
+	}
+	// 100 payloads: 60 of 10 bytes, 39 of 20 and one of 1000.
+	for i := 0; i < 100; i++ {
+		switch {
+		case i < 60:
+			c.add(10)
+		case i < 99:
+			c.add(20)
+		default:
+			c.add(1000)
+		}
+	}
+	want := PayloadSizes{Count: 100, Mean: (600 + 780 + 1000) / 100, P50: 10, P99: 1000, Max: 1000}
+	if got := *c.summary(); got != want {
+		t.Errorf("summary %+v, want %+v", got, want)
+	}
+}
+
+func TestMostFrequent(t *testing.T) {
+	counts := map[uint64]int{1: 5, 2: 9, 7: 5, 12: 5, 3: 1}
+	got := mostFrequent(counts, 3)
+	// Ties are ordered by the ID as printed, so id-12 comes before id-7.
+	want := []idCountPair{{"id-2", 9}, {"id-1", 5}, {"id-12", 5}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+	if got := mostFrequent(counts, 10); len(got) != len(counts) {
+		t.Errorf("got %d IDs with k above the number of IDs, want all %d", len(got), len(counts))
+	}
+}
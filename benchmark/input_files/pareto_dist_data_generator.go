@@ -1,14 +1,19 @@
 package input_files
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
-	"time"
+	"strings"
 )
 
 type idCountPair struct {
@@ -17,156 +22,253 @@ type idCountPair struct {
 }
 
 type Record struct {
-	ResourceType       string                 `json:"resource_type"`
-	ReporterType       string                 `json:"reporter_type"`
-	ReporterInstanceID string                 `json:"reporter_instance_id"`
-	LocalResourceID    string                 `json:"local_resource_id"`
-	APIHref            string                 `json:"api_href"`
-	ConsoleHref        string                 `json:"console_href"`
-	ReporterVersion    string                 `json:"reporter_version"`
-	Common             map[string]string      `json:"common,omitempty"`
-	Reporter           map[string]interface{} `json:"reporter,omitempty"`
+	ResourceType       string          `json:"resource_type"`
+	ReporterType       string          `json:"reporter_type"`
+	ReporterInstanceID string          `json:"reporter_instance_id"`
+	LocalResourceID    string          `json:"local_resource_id"`
+	APIHref            string          `json:"api_href"`
+	ConsoleHref        string          `json:"console_href"`
+	ReporterVersion    string          `json:"reporter_version"`
+	Common             json.RawMessage `json:"common,omitempty"`
+	Reporter           json.RawMessage `json:"reporter,omitempty"`
+	Category           string          `json:"category,omitempty"`
+	Tombstone          bool            `json:"tombstone,omitempty"`
 }
 
+// Categorize maps a resource ID to the category deciding the shape of its
+// records in the default workload.
 func Categorize(idNum uint64) string {
-	switch {
-	case idNum < 30:
-		return "cat1"
-	case idNum < 60:
-		return "cat2"
-	case idNum < 70:
-		return "cat3"
-	case idNum < 80:
-		return "cat4"
-	case idNum < 90:
-		return "cat5"
-	default:
-		return "cat6"
-	}
-}
-
-func generateK8sClusterReporter() map[string]interface{} {
-	return map[string]interface{}{
-		"external_cluster_id": randomString(8),
-		"cluster_status":      "READY",
-		"kube_version":        fmt.Sprintf("1.%d", rand.Intn(10)+20),
-		"kube_vendor":         "OPENSHIFT",
-		"vendor_version":      fmt.Sprintf("4.%d", rand.Intn(20)+1),
-		"cloud_platform":      "AWS_UPI",
-		"nodes": []map[string]interface{}{
-			{
-				"name":   fmt.Sprintf("%s.example.com", randomString(6)),
-				"cpu":    fmt.Sprintf("%dm", rand.Intn(10000)),
-				"memory": fmt.Sprintf("%dKi", rand.Intn(40000000)),
-				"labels": []map[string]string{
-					{"key": "has_monster_gpu", "value": "yes"},
-				},
-			},
-		},
-	}
-}
-
-func generateHostReporter() map[string]interface{} {
-	return map[string]interface{}{
-		"satellite_id":          uuid.New().String(),
-		"sub_manager_id":        uuid.New().String(),
-		"insights_inventory_id": uuid.New().String(),
-		"ansible_host":          fmt.Sprintf("host-%d", rand.Intn(100)),
-	}
-}
-
-func GenerateZipfIDsWithModuloCategory(n int, zipfMax uint64, modBase uint64, s, v float64, outputPath string) map[string][]string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	zipf := rand.NewZipf(r, s, v, zipfMax)
-
-	categorized := map[string][]string{
-		"cat1": {}, "cat2": {}, "cat3": {},
-		"cat4": {}, "cat5": {}, "cat6": {},
-	}
-
-	counts := make(map[string]int)
-	records := []Record{}
+	return DefaultWorkload().CategoryOf(idNum).Name
+}
 
-	for i := 0; i < n; i++ {
-		raw := zipf.Uint64()
-		modID := raw % modBase
-		idStr := "id-" + strconv.FormatUint(modID, 10)
-		fmt.Println(idStr)
-		counts[idStr]++
-
-		cat := Categorize(modID)
-		categorized[cat] = append(categorized[cat], idStr)
-
-		record := Record{
-			ResourceType:       "host",
-			ReporterInstanceID: "abc",
-			LocalResourceID:    strconv.FormatUint(modID, 10),
-			APIHref:            "www.example.com",
-			ConsoleHref:        "www.example.com",
-			ReporterVersion:    "123.2",
-		}
+// randomUUID draws a version 4 UUID from r rather than crypto/rand, so it is
+// reproduced by the same seed.
+func randomUUID(r *rand.Rand) string {
+	id, err := uuid.NewRandomFromReader(r)
+	if err != nil {
+		// *rand.Rand never fails to read.
+		panic(err)
+	}
+	return id.String()
+}
 
-		switch cat {
-		case "cat1":
-			record.ReporterType = "hbi"
-			record.Reporter = generateHostReporter()
-		case "cat2":
-			record.ReporterType = "acm"
-			record.Reporter = generateK8sClusterReporter()
-		case "cat3":
-			record.ReporterType = "hbi"
-			record.Common = map[string]string{"workspaceId": randomString(6)}
-		case "cat4":
-			record.ReporterType = "acm"
-			record.Common = map[string]string{"workspaceId": randomString(6)}
-		case "cat5":
-			record.ReporterType = "hbi"
-			record.Reporter = generateHostReporter()
-			record.Common = map[string]string{"workspaceId": randomString(6)}
-		case "cat6":
-			record.ReporterType = "acm"
-			record.Reporter = generateK8sClusterReporter()
-			record.Common = map[string]string{"workspaceId": randomString(6)}
-		}
+// GenerateZipfIDsWithModuloCategory writes n records with Zipf distributed
+// resource IDs to outputPath, see Zipf and GenerateRecords.
+func GenerateZipfIDsWithModuloCategory(n int, zipfMax uint64, modBase uint64, s, v float64, seed int64, outputPath string) (map[string]int, error) {
+	dist, err := NewZipf(modBase, zipfMax, s, v)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateRecords(DefaultWorkload(), dist, n, seed, outputPath)
+}
+
+// Metadata describes how an input file was generated. It is written next to
+// the file, see MetadataPath.
+type Metadata struct {
+	Records        int          `json:"records"`
+	Seed           int64        `json:"seed"`
+	Workload       string       `json:"workload"`
+	WorkloadSHA256 string       `json:"workload_sha256"`
+	Distribution   string       `json:"distribution"`
+	Params         Distribution `json:"params"`
+	DistinctIDs    int          `json:"distinct_ids"`
+	// ReporterBytes and CommonBytes describe the encoded payload sizes.
+	ReporterBytes *PayloadSizes `json:"reporter_bytes,omitempty"`
+	CommonBytes   *PayloadSizes `json:"common_bytes,omitempty"`
+	SHA256        string        `json:"sha256"`
+}
+
+// PayloadSizes summarizes the encoded sizes of one kind of payload.
+type PayloadSizes struct {
+	Count int `json:"count"`
+	Mean  int `json:"mean"`
+	P50   int `json:"p50"`
+	P99   int `json:"p99"`
+	Max   int `json:"max"`
+}
 
-		records = append(records, record)
+// sizeCounter summarizes payload sizes as they are generated. It counts
+// every distinct size rather than keeping every payload's, so its memory is
+// bounded by the largest size, not by the number of records.
+type sizeCounter struct {
+	counts map[int]int
+	count  int
+	total  int
+}
+
+func newSizeCounter() *sizeCounter {
+	return &sizeCounter{counts: map[int]int{}}
+}
+
+func (c *sizeCounter) add(size int) {
+	c.counts[size]++
+	c.count++
+	c.total += size
+}
+
+func (c *sizeCounter) summary() *PayloadSizes {
+	if c.count == 0 {
+		return nil
+	}
+	sizes := make([]int, 0, len(c.counts))
+	for size := range c.counts {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+	// at returns the size at rank i of all payloads in ascending order.
+	at := func(i int) int {
+		for _, size := range sizes {
+			if i < c.counts[size] {
+				return size
+			}
+			i -= c.counts[size]
+		}
+		return sizes[len(sizes)-1]
 	}
+	return &PayloadSizes{
+		Count: c.count,
+		Mean:  c.total / c.count,
+		P50:   at(c.count / 2),
+		P99:   at(c.count * 99 / 100),
+		Max:   sizes[len(sizes)-1],
+	}
+}
+
+func (p *PayloadSizes) String() string {
+	return fmt.Sprintf("%d payloads, mean %s, p50 %s, p99 %s, max %s", p.Count, kib(p.Mean), kib(p.P50), kib(p.P99), kib(p.Max))
+}
+
+func kib(bytes int) string {
+	return fmt.Sprintf("%.1fKB", float64(bytes)/1024)
+}
+
+// MetadataPath is where the metadata of the input file at path is written:
+// input.jsonl has its metadata in input.meta.json.
+func MetadataPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".meta.json"
+}
+
+// GenerateRecords writes n records of workload w with resource IDs drawn from
+// dist to outputPath and their Metadata to MetadataPath(outputPath). All randomness
+// comes from seed, so the same arguments always produce a byte-identical
+// file. Records are written as they are drawn and only counters are kept, so
+// memory does not grow with n. It returns the number of records per category.
+func GenerateRecords(w *Workload, dist Distribution, n int, seed int64, outputPath string) (map[string]int, error) {
+	r := rand.New(rand.NewSource(seed))
+	next := dist.Sampler(r)
 
-	// Write to file
 	file, err := os.Create(outputPath)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to create %s: %w", outputPath, err)
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	for _, r := range records {
-		if err := encoder.Encode(r); err != nil {
-			fmt.Fprintf(os.Stderr, "error writing record: %v\n", err)
+	hash := sha256.New()
+	out := bufio.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(out)
+
+	categorized := map[string]int{}
+	for _, c := range w.Categories {
+		categorized[c.Name] = 0
+	}
+	counts := make(map[uint64]int)
+	reporterSizes, commonSizes := newSizeCounter(), newSizeCounter()
+
+	for i := 0; i < n; i++ {
+		modID := next()
+		counts[modID]++
+
+		record := w.record(modID, r)
+		categorized[record.Category]++
+		if record.Reporter != nil {
+			reporterSizes.add(len(record.Reporter))
 		}
+		if record.Common != nil {
+			commonSizes.add(len(record.Common))
+		}
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("error writing record: %w", err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", outputPath, err)
 	}
 
-	// Print sorted frequency summary
-	fmt.Println("\nSorted IDs by frequency:")
-	var sorted []idCountPair
-	for id, count := range counts {
-		sorted = append(sorted, idCountPair{ID: id, Count: count})
+	meta := Metadata{
+		Records:        n,
+		Seed:           seed,
+		Workload:       w.Name,
+		WorkloadSHA256: w.SHA256(),
+		Distribution:   dist.Name(),
+		Params:         dist,
+		DistinctIDs:    len(counts),
+		ReporterBytes:  reporterSizes.summary(),
+		CommonBytes:    commonSizes.summary(),
+		SHA256:         hex.EncodeToString(hash.Sum(nil)),
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
 	}
-	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Count > sorted[j].Count
-	})
-	for _, pair := range sorted {
+	if err := os.WriteFile(MetadataPath(outputPath), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	// Print sorted frequency summary
+	fmt.Printf("\n%d distinct IDs, most frequent:\n", len(counts))
+	for _, pair := range mostFrequent(counts, topIDs) {
 		fmt.Printf("%s: %d\n", pair.ID, pair.Count)
 	}
+	if meta.ReporterBytes != nil {
+		fmt.Printf("📦 Reporter payloads: %s\n", meta.ReporterBytes)
+	}
+	if meta.CommonBytes != nil {
+		fmt.Printf("📦 Common payloads: %s\n", meta.CommonBytes)
+	}
 
-	return categorized
+	return categorized, nil
 }
 
-func randomString(n int) string {
+// mostFrequent returns the k most frequent IDs in counts, most frequent
+// first and ties by ID, without sorting all of them.
+func mostFrequent(counts map[uint64]int, k int) []idCountPair {
+	before := func(a, b idCountPair) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.ID < b.ID
+	}
+	var top []idCountPair
+	for id, count := range counts {
+		if len(top) == k && count < top[k-1].Count {
+			continue
+		}
+		pair := idCountPair{ID: "id-" + strconv.FormatUint(id, 10), Count: count}
+		if len(top) == k && !before(pair, top[k-1]) {
+			continue
+		}
+		at := sort.Search(len(top), func(i int) bool { return before(pair, top[i]) })
+		top = append(top, idCountPair{})
+		copy(top[at+1:], top[at:])
+		top[at] = pair
+		if len(top) > k {
+			top = top[:k]
+		}
+	}
+	return top
+}
+
+// topIDs is the number of IDs listed in the frequency summary.
+const topIDs = 20
+
+func randomString(r *rand.Rand, n int) string {
 	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[r.Intn(len(letters))]
 	}
 	return string(b)
 }
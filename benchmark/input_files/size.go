@@ -0,0 +1,140 @@
+package input_files
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Size is a number drawn for every record, such as the number of nodes of a
+// cluster or the size of a payload. In a spec it is either a fixed number,
+// optionally with a KB or MB suffix, or a distribution:
+//
+//	{distribution: uniform, min: 1, max: 500}
+//	{distribution: lognormal, median: 40, sigma: 1, max: 2000}
+//	{distribution: pareto, min: 1KB, alpha: 1.5, max: 1MB}
+//
+// Draws are clamped to [min, max]. Lognormal and pareto sizes need a max, as
+// their tail would otherwise produce arbitrarily large records.
+type Size struct {
+	Distribution string  `yaml:"distribution"`
+	Value        int     `yaml:"-"`
+	Min          int     `yaml:"-"`
+	Max          int     `yaml:"-"`
+	Median       int     `yaml:"-"`
+	Sigma        float64 `yaml:"sigma"`
+	Alpha        float64 `yaml:"alpha"`
+}
+
+func (s *Size) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		v, err := parseQuantity(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		*s = Size{Distribution: "fixed", Value: v}
+		return nil
+	}
+
+	// Decode the plain fields first, then the quantities, which may carry a
+	// unit.
+	type plain Size
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	var quantities struct {
+		Value  string `yaml:"value"`
+		Min    string `yaml:"min"`
+		Max    string `yaml:"max"`
+		Median string `yaml:"median"`
+	}
+	if err := node.Decode(&quantities); err != nil {
+		return err
+	}
+	for _, q := range []struct {
+		text string
+		into *int
+	}{
+		{quantities.Value, &s.Value},
+		{quantities.Min, &s.Min},
+		{quantities.Max, &s.Max},
+		{quantities.Median, &s.Median},
+	} {
+		if q.text == "" {
+			continue
+		}
+		v, err := parseQuantity(q.text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		*q.into = v
+	}
+
+	if s.Max != 0 && s.Max < s.Min {
+		return fmt.Errorf("line %d: max is below min", node.Line)
+	}
+	switch s.Distribution {
+	case "fixed":
+	case "uniform":
+		if s.Max == 0 {
+			return fmt.Errorf("line %d: uniform needs max", node.Line)
+		}
+	case "lognormal":
+		if s.Median <= 0 || s.Sigma <= 0 || s.Max == 0 {
+			return fmt.Errorf("line %d: lognormal needs median > 0, sigma > 0 and max", node.Line)
+		}
+	case "pareto":
+		if s.Min <= 0 || s.Alpha <= 0 || s.Max == 0 {
+			return fmt.Errorf("line %d: pareto needs min > 0, alpha > 0 and max", node.Line)
+		}
+	default:
+		return fmt.Errorf("line %d: unknown size distribution %q", node.Line, s.Distribution)
+	}
+	return nil
+}
+
+// parseQuantity parses a non-negative number with an optional KB or MB
+// suffix; KB is 1024 bytes.
+func parseQuantity(text string) (int, error) {
+	unit := 1
+	number := strings.TrimSpace(text)
+	for _, suffix := range []struct {
+		name string
+		size int
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}} {
+		if strings.HasSuffix(strings.ToUpper(number), suffix.name) {
+			unit = suffix.size
+			number = strings.TrimSpace(number[:len(number)-len(suffix.name)])
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("bad size %q", text)
+	}
+	return int(v * float64(unit)), nil
+}
+
+// Draw returns the next size.
+func (s *Size) Draw(r *rand.Rand) int {
+	var v float64
+	switch s.Distribution {
+	case "fixed":
+		return s.Value
+	case "uniform":
+		return s.Min + r.Intn(s.Max-s.Min+1)
+	case "lognormal":
+		v = float64(s.Median) * math.Exp(s.Sigma*r.NormFloat64())
+	case "pareto":
+		// 1-Float64 is in (0, 1], which keeps the power finite.
+		v = float64(s.Min) / math.Pow(1-r.Float64(), 1/s.Alpha)
+	}
+	if v > float64(s.Max) {
+		return s.Max
+	}
+	return max(s.Min, int(v))
+}
@@ -0,0 +1,111 @@
+package input_files
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEncodePayloadReachesTarget(t *testing.T) {
+	payloads := map[string]func() map[string]interface{}{
+		"empty":     func() map[string]interface{} { return map[string]interface{}{} },
+		"one field": func() map[string]interface{} { return map[string]interface{}{"a": "b"} },
+		"escaped":   func() map[string]interface{} { return map[string]interface{}{"html": "<a & b>", "n": 3} },
+		"nested": func() map[string]interface{} {
+			return map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"name": "x"}}}
+		},
+	}
+	for name, payload := range payloads {
+		for _, target := range []int{64, 100, 1000, 16 << 10} {
+			size := &Size{Distribution: "fixed", Value: target}
+			data := encodePayload(payload(), size, rand.New(rand.NewSource(1)))
+			if len(data) != target {
+				t.Errorf("%s payload padded to %d: got %d bytes: %s", name, target, len(data), truncate(string(data)))
+			}
+		}
+	}
+}
+
+func TestEncodePayloadBeyondTarget(t *testing.T) {
+	payload := map[string]interface{}{"key": strings.Repeat("x", 100)}
+	data := encodePayload(payload, &Size{Distribution: "fixed", Value: 10}, rand.New(rand.NewSource(1)))
+	if strings.Contains(string(data), paddingKey) {
+		t.Errorf("payload beyond its target was padded: %s", truncate(string(data)))
+	}
+}
+
+func TestEncodePayloadBelowPaddingOverhead(t *testing.T) {
+	payload := func() map[string]interface{} { return map[string]interface{}{"a": "b"} }
+	unpadded := len(`{"a":"b"}`)
+	// `,"padding":""` is 14 bytes: targets short of that round down to the
+	// unpadded payload, and a target of exactly that pads with "".
+	overhead := len(paddingKey) + 6
+	for target := unpadded; target <= unpadded+overhead; target++ {
+		data := encodePayload(payload(), &Size{Distribution: "fixed", Value: target}, rand.New(rand.NewSource(1)))
+		want := unpadded
+		if target == unpadded+overhead {
+			want = target
+		}
+		if len(data) != want {
+			t.Errorf("target %d: got %d bytes, want %d: %s", target, len(data), want, data)
+		}
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > 80 {
+		return s[:80] + "..."
+	}
+	return s
+}
+
+func TestSizeUnmarshal(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Size
+		wantErr bool
+	}{
+		{spec: "40", want: Size{Distribution: "fixed", Value: 40}},
+		{spec: "16KB", want: Size{Distribution: "fixed", Value: 16 << 10}},
+		{spec: "{distribution: uniform, min: 1, max: 500}", want: Size{Distribution: "uniform", Min: 1, Max: 500}},
+		{spec: "{distribution: lognormal, median: 40, sigma: 1, max: 2KB}", want: Size{Distribution: "lognormal", Median: 40, Sigma: 1, Max: 2 << 10}},
+		{spec: "{distribution: pareto, min: 1KB, alpha: 1.5, max: 1MB}", want: Size{Distribution: "pareto", Min: 1 << 10, Alpha: 1.5, Max: 1 << 20}},
+		{spec: "{distribution: uniform, min: 1}", wantErr: true},
+		{spec: "{distribution: lognormal, median: 40, sigma: 1}", wantErr: true},
+		{spec: "{distribution: pareto, min: 1KB, alpha: 1.5}", wantErr: true},
+		{spec: "{distribution: pareto, min: 2KB, alpha: 1.5, max: 1KB}", wantErr: true},
+		{spec: "{distribution: normal, max: 1KB}", wantErr: true},
+		{spec: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Size
+		err := yaml.Unmarshal([]byte(tt.spec), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestSizeDrawStaysInBounds(t *testing.T) {
+	sizes := []Size{
+		{Distribution: "uniform", Min: 1, Max: 500},
+		{Distribution: "lognormal", Median: 40, Sigma: 3, Max: 2000},
+		{Distribution: "pareto", Min: 1 << 10, Alpha: 0.3, Max: 1 << 20},
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, s := range sizes {
+		for i := 0; i < 10000; i++ {
+			if v := s.Draw(r); v < s.Min || v > s.Max {
+				t.Fatalf("%s drew %d, outside [%d, %d]", s.Distribution, v, s.Min, s.Max)
+			}
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package input_files
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workload declares the records the generator writes: which reporters report
+// which resources, with which payloads. It is read from YAML or JSON, see
+// workloads/default.yaml.
+type Workload struct {
+	Name            string `yaml:"name"`
+	ResourceType    string `yaml:"resource_type"`
+	APIHref         string `yaml:"api_href"`
+	ConsoleHref     string `yaml:"console_href"`
+	ReporterVersion string `yaml:"reporter_version"`
+
+	// Reporters holds the reporter payload template per reporter type and
+	// Common the common payload template.
+	Reporters  map[string]*Template `yaml:"reporters"`
+	Common     *Template            `yaml:"common"`
+	Categories []WorkloadCategory   `yaml:"categories"`
+
+	totalWeight uint64
+	sha256      string
+}
+
+// WorkloadCategory is one kind of resource in a workload. Resource IDs are
+// assigned to categories by id % the sum of all weights, in the order the
+// categories are listed, so a resource always keeps its category.
+type WorkloadCategory struct {
+	Name         string `yaml:"name"`
+	Weight       uint64 `yaml:"weight"`
+	ResourceType string `yaml:"resource_type"`
+	ReporterType string `yaml:"reporter_type"`
+	// ReporterInstanceIDs lists the reporter instances; ReporterInstances
+	// instead names that many as <reporter type>-<n>. Resources are spread
+	// over the instances by ID.
+	ReporterInstanceIDs []string `yaml:"reporter_instance_ids"`
+	ReporterInstances   int      `yaml:"reporter_instances"`
+	// Payload is reporter, common or both.
+	Payload string `yaml:"payload"`
+	// Tombstone is the probability that a record is marked as a tombstone.
+	// It is only a marker: the options store it on the representations they
+	// write, and nothing is deleted.
+	Tombstone float64 `yaml:"tombstone"`
+	// ReporterBytes and CommonBytes are the sizes the encoded payloads are
+	// padded to, see pad.
+	ReporterBytes *Size `yaml:"reporter_bytes"`
+	CommonBytes   *Size `yaml:"common_bytes"`
+}
+
+//go:embed workloads/default.yaml
+var defaultWorkloadSpec []byte
+
+var defaultWorkload *Workload
+
+// The default workload is parsed in init rather than a variable initializer:
+// parsing reaches the placeholder pattern only through yaml's reflection, so
+// the initializer could run before it is compiled.
+func init() {
+	w, err := ParseWorkload(defaultWorkloadSpec)
+	if err != nil {
+		panic(fmt.Sprintf("default workload: %v", err))
+	}
+	defaultWorkload = w
+}
+
+// DefaultWorkload returns the workload the benchmark inputs were generated
+// with.
+func DefaultWorkload() *Workload {
+	return defaultWorkload
+}
+
+// LoadWorkload reads a workload spec from a YAML or JSON file.
+func LoadWorkload(path string) (*Workload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := ParseWorkload(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return w, nil
+}
+
+// ParseWorkload parses and checks a workload spec. JSON is valid YAML, so
+// both are accepted.
+func ParseWorkload(data []byte) (*Workload, error) {
+	var w Workload
+	if err := yaml.Unmarshal(data, &w); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	w.sha256 = hex.EncodeToString(sum[:])
+
+	if len(w.Categories) == 0 {
+		return nil, fmt.Errorf("workload declares no categories")
+	}
+	for i := range w.Categories {
+		c := &w.Categories[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("cat%d", i+1)
+		}
+		if c.Weight == 0 {
+			return nil, fmt.Errorf("category %s: weight must be positive", c.Name)
+		}
+		if c.ResourceType == "" {
+			c.ResourceType = w.ResourceType
+		}
+		if c.ReporterType == "" {
+			return nil, fmt.Errorf("category %s: reporter_type is required", c.Name)
+		}
+		for n := 1; n <= c.ReporterInstances; n++ {
+			c.ReporterInstanceIDs = append(c.ReporterInstanceIDs, fmt.Sprintf("%s-%d", c.ReporterType, n))
+		}
+		if len(c.ReporterInstanceIDs) == 0 {
+			return nil, fmt.Errorf("category %s: needs reporter_instance_ids or reporter_instances", c.Name)
+		}
+		switch c.Payload {
+		case "reporter", "both":
+			if w.Reporters[c.ReporterType] == nil {
+				return nil, fmt.Errorf("category %s: no payload template for reporter %q", c.Name, c.ReporterType)
+			}
+		case "common":
+		default:
+			return nil, fmt.Errorf("category %s: payload must be reporter, common or both, got %q", c.Name, c.Payload)
+		}
+		if (c.Payload == "common" || c.Payload == "both") && w.Common == nil {
+			return nil, fmt.Errorf("category %s: no common payload template", c.Name)
+		}
+		if c.Tombstone < 0 || c.Tombstone > 1 {
+			return nil, fmt.Errorf("category %s: tombstone must be between 0 and 1", c.Name)
+		}
+		w.totalWeight += c.Weight
+	}
+	return &w, nil
+}
+
+// SHA256 is the checksum of the spec the workload was parsed from.
+func (w *Workload) SHA256() string {
+	return w.sha256
+}
+
+// CategoryOf returns the category of resource id.
+func (w *Workload) CategoryOf(id uint64) *WorkloadCategory {
+	bucket := id % w.totalWeight
+	for i := range w.Categories {
+		c := &w.Categories[i]
+		if bucket < c.Weight {
+			return c
+		}
+		bucket -= c.Weight
+	}
+	panic("unreachable: buckets cover the total weight")
+}
+
+// record builds the record of resource id, drawing its payloads from r.
+func (w *Workload) record(id uint64, r *rand.Rand) Record {
+	c := w.CategoryOf(id)
+	record := Record{
+		ResourceType:       c.ResourceType,
+		ReporterType:       c.ReporterType,
+		ReporterInstanceID: c.ReporterInstanceIDs[(id/w.totalWeight)%uint64(len(c.ReporterInstanceIDs))],
+		LocalResourceID:    strconv.FormatUint(id, 10),
+		APIHref:            w.APIHref,
+		ConsoleHref:        w.ConsoleHref,
+		ReporterVersion:    w.ReporterVersion,
+		Category:           c.Name,
+	}
+	if c.Payload == "reporter" || c.Payload == "both" {
+		record.Reporter = encodePayload(w.Reporters[c.ReporterType].expandMap(id, r), c.ReporterBytes, r)
+	}
+	if c.Payload == "common" || c.Payload == "both" {
+		record.Common = encodePayload(w.Common.expandMap(id, r), c.CommonBytes, r)
+	}
+	if c.Tombstone > 0 {
+		record.Tombstone = r.Float64() < c.Tombstone
+	}
+	return record
+}
+
+// paddingKey is the payload field holding the padding, see encodePayload.
+const paddingKey = "padding"
+
+// encodePayload encodes payload. With a target size it adds a random string
+// under paddingKey so the encoding reaches the drawn number of bytes; random
+// text does not compress, so padded payloads are stored, and TOASTed, at
+// their full size. Payloads already at or beyond the target are left as
+// they are. So are payloads whose target lies less than the padding field's
+// own overhead above them: such targets round down to the unpadded size.
+func encodePayload(payload map[string]interface{}, target *Size, r *rand.Rand) json.RawMessage {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		// Payloads are decoded from YAML, which only yields encodable values.
+		panic(err)
+	}
+	if target == nil {
+		return data
+	}
+	// The padding field adds `"padding":""` around the string, plus a comma
+	// unless the payload is empty.
+	overhead := len(paddingKey) + 5
+	if len(payload) > 0 {
+		overhead++
+	}
+	n := target.Draw(r) - len(data) - overhead
+	if n < 0 {
+		return data
+	}
+	payload[paddingKey] = randomString(r, n)
+	data, err = json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Template is a payload template. It keeps the YAML node so fields are
+// expanded, and random values drawn, in the order they are listed.
+//
+// A mapping of the form {$repeat: <size>, $item: <template>} expands to a
+// list of a drawn number of items, e.g. the nodes of a cluster, see Size.
+type Template struct {
+	node    *yaml.Node
+	repeats map[*yaml.Node]*repeat
+}
+
+type repeat struct {
+	count *Size
+	item  *yaml.Node
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*(\w+)((?:\s+\d+)*)\s*\}\}`)
+
+func (t *Template) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: payload template must be a mapping", node.Line)
+	}
+	t.node = node
+	t.repeats = map[*yaml.Node]*repeat{}
+	return t.check(node)
+}
+
+// check reports unknown placeholders and malformed repeats up front rather
+// than while generating.
+func (t *Template) check(node *yaml.Node) error {
+	if rep, err := repeatOf(node); err != nil {
+		return err
+	} else if rep != nil {
+		t.repeats[node] = rep
+		return t.check(rep.item)
+	}
+	if node.Kind == yaml.ScalarNode {
+		for _, m := range placeholder.FindAllStringSubmatch(node.Value, -1) {
+			args := strings.Fields(m[2])
+			ok := false
+			switch m[1] {
+			case "uuid", "id":
+				ok = len(args) == 0
+			case "string":
+				ok = len(args) == 1
+			case "int":
+				switch len(args) {
+				case 1:
+					n, _ := strconv.Atoi(args[0])
+					ok = n > 0
+				case 2:
+					lo, _ := strconv.Atoi(args[0])
+					hi, _ := strconv.Atoi(args[1])
+					ok = hi > lo
+				}
+			}
+			if !ok {
+				return fmt.Errorf("line %d: bad placeholder %q", node.Line, m[0])
+			}
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := t.check(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repeatOf returns the repeat node describes, or nil if it is none.
+func repeatOf(node *yaml.Node) (*repeat, error) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || node.Content[0].Value != "$repeat" {
+		return nil, nil
+	}
+	var rep repeat
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key, value := node.Content[i].Value, node.Content[i+1]; key {
+		case "$repeat":
+			rep.count = &Size{}
+			if err := value.Decode(rep.count); err != nil {
+				return nil, err
+			}
+		case "$item":
+			rep.item = value
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q next to $repeat", node.Content[i].Line, key)
+		}
+	}
+	if rep.item == nil {
+		return nil, fmt.Errorf("line %d: $repeat without $item", node.Line)
+	}
+	return &rep, nil
+}
+
+func (t *Template) expandMap(id uint64, r *rand.Rand) map[string]interface{} {
+	return t.expand(t.node, id, r).(map[string]interface{})
+}
+
+func (t *Template) expand(node *yaml.Node, id uint64, r *rand.Rand) interface{} {
+	if rep := t.repeats[node]; rep != nil {
+		items := make([]interface{}, rep.count.Draw(r))
+		for i := range items {
+			items[i] = t.expand(rep.item, id, r)
+		}
+		return items
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = t.expand(node.Content[i+1], id, r)
+		}
+		return m
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(node.Content))
+		for i, child := range node.Content {
+			items[i] = t.expand(child, id, r)
+		}
+		return items
+	case yaml.AliasNode:
+		return t.expand(node.Alias, id, r)
+	}
+
+	if node.Tag != "!!str" {
+		var v interface{}
+		if err := node.Decode(&v); err == nil {
+			return v
+		}
+	}
+	return placeholder.ReplaceAllStringFunc(node.Value, func(s string) string {
+		m := placeholder.FindStringSubmatch(s)
+		var args []int
+		for _, a := range strings.Fields(m[2]) {
+			n, _ := strconv.Atoi(a)
+			args = append(args, n)
+		}
+		switch m[1] {
+		case "uuid":
+			return randomUUID(r)
+		case "id":
+			return strconv.FormatUint(id, 10)
+		case "string":
+			return randomString(r, args[0])
+		default: // int, checked when the template was parsed
+			if len(args) == 2 {
+				return strconv.Itoa(args[0] + r.Intn(args[1]-args[0]))
+			}
+			return strconv.Itoa(r.Intn(args[0]))
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package benchmark
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// IsolationLevel is the transaction isolation a run processes records under.
+type IsolationLevel string
+
+const (
+	// IsolationNone runs every statement of a record in its own implicit
+	// transaction instead of wrapping the record in one. Failed records are
+	// not retried, as their earlier statements stay committed.
+	IsolationNone           IsolationLevel = "none"
+	IsolationReadCommitted  IsolationLevel = "read-committed"
+	IsolationRepeatableRead IsolationLevel = "repeatable-read"
+	IsolationSerializable   IsolationLevel = "serializable"
+)
+
+// IsolationLevels lists every supported level, weakest first.
+var IsolationLevels = []IsolationLevel{IsolationNone, IsolationReadCommitted, IsolationRepeatableRead, IsolationSerializable}
+
+// ParseIsolationLevel validates an isolation level name.
+func ParseIsolationLevel(name string) (IsolationLevel, error) {
+	for _, level := range IsolationLevels {
+		if string(level) == name {
+			return level, nil
+		}
+	}
+	names := make([]string, len(IsolationLevels))
+	for i, level := range IsolationLevels {
+		names[i] = string(level)
+	}
+	return "", fmt.Errorf("unknown isolation level %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// orDefault returns the level used when none is configured, which is the
+// serializable isolation the harness has always used.
+func (l IsolationLevel) orDefault() IsolationLevel {
+	if l == "" {
+		return IsolationSerializable
+	}
+	return l
+}
+
+// txOptions returns the options to begin a transaction with, or nil for
+// IsolationNone.
+func (l IsolationLevel) txOptions() *sql.TxOptions {
+	switch l.orDefault() {
+	case IsolationReadCommitted:
+		return &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	case IsolationRepeatableRead:
+		return &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	case IsolationSerializable:
+		return &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/go-db-bench/config"
+)
+
+// Environment is what a session ran on, beyond the harness config: the Go
+// runtime, the machine and the Postgres server.
+type Environment struct {
+	GoVersion  string       `json:"go_version"`
+	OS         string       `json:"os"`
+	Arch       string       `json:"arch"`
+	NumCPU     int          `json:"num_cpu"`
+	GOMAXPROCS int          `json:"gomaxprocs"`
+	Postgres   PostgresInfo `json:"postgres"`
+}
+
+// PostgresInfo is the server version and the settings that affect the
+// benchmark most.
+type PostgresInfo struct {
+	Version       string `json:"version"`
+	VersionNumber int    `json:"version_num"`
+	// Settings maps the names in postgresSettings to their current value,
+	// with unit, as shown by SHOW.
+	Settings map[string]string `json:"settings"`
+}
+
+// postgresSettings are captured for every session.
+var postgresSettings = []string{
+	"shared_buffers",
+	"work_mem",
+	"maintenance_work_mem",
+	"effective_cache_size",
+	"synchronous_commit",
+	"fsync",
+	"full_page_writes",
+	"wal_level",
+	"max_wal_size",
+	"checkpoint_timeout",
+	"max_connections",
+	"random_page_cost",
+	"jit",
+	"default_transaction_isolation",
+	"autovacuum",
+}
+
+// CaptureEnvironment fills in the environment of session, querying the
+// server behind db. The benchmark database is recreated for every run, so
+// the server is queried through the postgres database.
+func (s *Session) CaptureEnvironment(db config.DBConfig) error {
+	s.Environment = Environment{
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+
+	admin := db
+	admin.DBName = "postgres"
+	conn, err := config.OpenDB(admin)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	defer sqlDB.Close()
+
+	pg := &s.Environment.Postgres
+	if err := conn.Raw("SELECT version(), current_setting('server_version_num')::int").Row().Scan(&pg.Version, &pg.VersionNumber); err != nil {
+		return fmt.Errorf("failed to query server version: %w", err)
+	}
+
+	var settings []struct {
+		Name    string
+		Setting string
+	}
+	if err := conn.Raw("SELECT name, current_setting(name) AS setting FROM pg_settings WHERE name IN ?", postgresSettings).Scan(&settings).Error; err != nil {
+		return fmt.Errorf("failed to query server settings: %w", err)
+	}
+	pg.Settings = make(map[string]string, len(settings))
+	for _, setting := range settings {
+		pg.Settings[setting.Name] = setting.Setting
+	}
+	return nil
+}
+
+// PrintManifest writes what is needed to reproduce session: where its input
+// and code came from, the harness config and the environment.
+func PrintManifest(w io.Writer, s *Session) {
+	commit := s.GitCommit
+	if s.GitDirty {
+		commit += " (dirty)"
+	}
+	c := s.Config
+	env := s.Environment
+
+	fmt.Fprintf(w, "\n🧾 Session %s\n", s.ID)
+	fmt.Fprintf(w, "  Option:      %s (tag %q)\n", s.Option, s.Tag)
+	fmt.Fprintf(w, "  Input:       %s (sha256 %.12s)\n", s.Input, s.InputSHA256)
+	fmt.Fprintf(w, "  Commit:      %s\n", commit)
+	fmt.Fprintf(w, "  Config:      %d runs, %d workers (%s), isolation %s, retries %d (%v–%v), explain %t\n",
+		c.RunCount, c.Concurrency, c.Mode, joinLevels(c.Isolation), c.MaxRetries, c.InitialBackoff, c.MaxBackoff, c.Explain)
+	if c.Warmup != (WarmupPolicy{}) {
+		fmt.Fprintf(w, "  Warm-up:     %d records, %s, steady state %t\n", c.Warmup.Records, c.Warmup.Duration, c.Warmup.SteadyState)
+	}
+	fmt.Fprintf(w, "  Host:        %s, %s/%s, %d CPUs (GOMAXPROCS %d), %s\n", s.Host, env.OS, env.Arch, env.NumCPU, env.GOMAXPROCS, env.GoVersion)
+	fmt.Fprintf(w, "  Postgres:    %s\n", env.Postgres.Version)
+
+	names := make([]string, 0, len(env.Postgres.Settings))
+	for name := range env.Postgres.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "    %-30s %s\n", name, env.Postgres.Settings[name])
+	}
+}
+
+func joinLevels(levels []IsolationLevel) string {
+	names := make([]string, len(levels))
+	for i, level := range levels {
+		names[i] = string(level)
+	}
+	return strings.Join(names, ", ")
+}
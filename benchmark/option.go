@@ -0,0 +1,89 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Option is a schema variant under test. Implementations register themselves
+// with RegisterOption from an init function so that a new variant only needs
+// its own file.
+type Option interface {
+	// Name is the identifier used to select the option, e.g. "option1".
+	Name() string
+	// Models are the GORM models auto-migrated for the option.
+	Models() []interface{}
+	// ExtraDDL is executed after migration for schema objects GORM cannot
+	// express, such as partial indexes.
+	ExtraDDL() []string
+	// ProcessRecord applies one input record inside the transaction tx and
+	// reports which path it took. The statements it runs are timed by
+	// TimingPlugin; wrapping tx with Label names the step a statement is
+	// recorded under.
+	ProcessRecord(tx *gorm.DB, rec InputRecord) (Path, error)
+}
+
+var (
+	optionsMu sync.RWMutex
+	options   = map[string]Option{}
+)
+
+// RegisterOption makes an option available by name. It panics if an option
+// with the same name is already registered.
+func RegisterOption(option Option) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	name := option.Name()
+	if _, dup := options[name]; dup {
+		panic("benchmark: RegisterOption called twice for option " + name)
+	}
+	options[name] = option
+}
+
+// LookupOption returns the registered option with the given name.
+func LookupOption(name string) (Option, error) {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+
+	option, ok := options[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema option %q (registered: %s)", name, strings.Join(optionNamesLocked(), ", "))
+	}
+	return option, nil
+}
+
+// OptionNames returns the names of all registered options, sorted.
+func OptionNames() []string {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return optionNamesLocked()
+}
+
+func optionNamesLocked() []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MigrateOption creates the tables and extra schema objects of a single option.
+func MigrateOption(db *gorm.DB, option Option) error {
+	// GORM auto-migration to recreate tables
+	if err := db.AutoMigrate(option.Models()...); err != nil {
+		return err
+	}
+
+	for _, ddl := range option.ExtraDDL() {
+		if err := db.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("failed to apply DDL for %s: %w", option.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package options
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/db/schemas/option1_denormalized_reference_2_rep_tables/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func init() {
+	benchmark.RegisterOption(option1{})
+}
+
+// option1 keeps denormalized representation references alongside separate
+// common and reporter representation tables.
+type option1 struct{}
+
+func (option1) Name() string { return "option1" }
+
+func (option1) Models() []interface{} {
+	return []interface{}{
+		&models.Resource{},
+		&models.CommonRepresentation{},
+		&models.ReporterRepresentation{},
+		&models.RepresentationReference{},
+	}
+}
+
+func (option1) ExtraDDL() []string { return nil }
+
+func (option1) ProcessRecord(tx *gorm.DB, rec benchmark.InputRecord) (benchmark.Path, error) {
+	return ProcessRecordOption1(tx, rec)
+}
+
+func ProcessRecordOption1(tx *gorm.DB, rec benchmark.InputRecord) (benchmark.Path, error) {
+	var refs []models.RepresentationReference
+	if err := selectRefsOption1(benchmark.Label(tx, "select_refs_join"), rec, &refs).Error; err != nil {
+		return "", err
+	}
+
+	path := benchmark.PathCreate
+	if len(refs) == 0 {
+
+		resourceID := uuid.New()
+		res := models.Resource{
+			ID:   resourceID,
+			Type: rec.ResourceType,
+		}
+
+		if err := insertResource(benchmark.Label(tx, "insert_resource"), res).Error; err != nil {
+			return "", err
+		}
+
+		refsToCreate := []models.RepresentationReference{
+			{ResourceID: resourceID, LocalResourceID: rec.LocalResourceID, ReporterType: rec.ReporterType,
+				ReporterInstanceID: rec.ReporterInstanceID, ResourceType: rec.ResourceType, RepresentationVersion: 1,
+				Generation: 1, Tombstone: false},
+			{ResourceID: resourceID, LocalResourceID: resourceID.String(), ReporterType: "inventory",
+				RepresentationVersion: 1, Generation: 1, Tombstone: false},
+		}
+
+		if err := insertRepresentationReferences(benchmark.Label(tx, "insert_refs"), refsToCreate).Error; err != nil {
+			return "", err
+		}
+
+		var commonData datatypes.JSON
+		if rec.Common == nil || len(rec.Common) == 0 {
+			defaultCommon := map[string]string{"workspaceId": "default"}
+			bytes, _ := json.Marshal(defaultCommon)
+			commonData = bytes
+		} else {
+			commonData = datatypes.JSON(rec.Common)
+		}
+
+		commonRep := &models.CommonRepresentation{
+			BaseRepresentation: models.BaseRepresentation{
+				Data: commonData,
+			},
+			LocalResourceID: resourceID.String(),
+			Version:         1,
+			ReporterType:    "inventory",
+			ResourceType:    rec.ResourceType,
+		}
+
+		if err := insertCommonRepresentation(benchmark.Label(tx, "insert_common_rep"), commonRep).Error; err != nil {
+			return "", err
+		}
+
+		var reporterData datatypes.JSON
+		if rec.Reporter == nil || len(rec.Reporter) == 0 {
+			reporterData = []byte(`{}`)
+		} else {
+			reporterData = datatypes.JSON(rec.Reporter)
+		}
+
+		reporterRep := &models.ReporterRepresentation{
+			BaseRepresentation: models.BaseRepresentation{
+				Data: reporterData,
+			},
+			LocalResourceID: rec.LocalResourceID, ReporterType: rec.ReporterType,
+			ResourceType: rec.ResourceType, Version: 1,
+			ReporterVersion: rec.ReporterVersion, ReporterInstanceID: rec.ReporterInstanceID,
+			APIHref: rec.APIHref, ConsoleHref: rec.ConsoleHref, CommonVersion: 1,
+			Tombstone: rec.Tombstone, Generation: 1,
+		}
+		if err := insertReporterRepresentation(benchmark.Label(tx, "insert_reporter_rep"), reporterRep).Error; err != nil {
+			return "", err
+		}
+	} else {
+		var commonVersion int
+		var reporterVersion int
+		var updatedCommon, updatedReporter bool
+
+		for _, ref := range refs {
+			if ref.ReporterType == "inventory" {
+				commonVersion = ref.RepresentationVersion
+			} else if ref.ReporterType == rec.ReporterType {
+				reporterVersion = ref.RepresentationVersion
+			}
+		}
+
+		// Update case: bump version and generation
+		for _, ref := range refs {
+			ref.RepresentationVersion++
+			if ref.ReporterType == "inventory" {
+				if rec.Common != nil {
+					newCommonVersion := commonVersion + 1
+					commonRep := &models.CommonRepresentation{
+						BaseRepresentation: models.BaseRepresentation{
+
+							Data: datatypes.JSON(rec.Common),
+						},
+						LocalResourceID: refs[0].ResourceID.String(),
+						Version:         newCommonVersion,
+						ReporterType:    "inventory",
+						ResourceType:    rec.ResourceType,
+					}
+					if err := insertCommonRepresentation(benchmark.Label(tx, "insert_common_rep"), commonRep).Error; err != nil {
+						return "", err
+					}
+
+					if err := updateCommonRepresentationVersion(benchmark.Label(tx, "update_common_rep_ref"), refs[0].ResourceID, newCommonVersion).Error; err != nil {
+						return "", err
+					}
+					updatedCommon = true
+				}
+			} else {
+				if rec.Reporter != nil {
+					newReporterVersion := reporterVersion + 1
+					reporterRep := &models.ReporterRepresentation{
+						BaseRepresentation: models.BaseRepresentation{
+							Data: datatypes.JSON(rec.Reporter),
+						},
+						LocalResourceID:    rec.LocalResourceID,
+						ReporterType:       rec.ReporterType,
+						ResourceType:       rec.ResourceType,
+						Version:            newReporterVersion,
+						ReporterVersion:    rec.ReporterVersion,
+						ReporterInstanceID: rec.ReporterInstanceID,
+						APIHref:            rec.APIHref,
+						ConsoleHref:        rec.ConsoleHref,
+						CommonVersion:      refs[0].RepresentationVersion,
+						Tombstone:          rec.Tombstone,
+						Generation:         ref.Generation,
+					}
+					if err := insertReporterRepresentation(benchmark.Label(tx, "insert_reporter_rep"), reporterRep).Error; err != nil {
+						return "", err
+					}
+
+					// Update representation_reference
+					if err := updateReporterRepresentationVersion(benchmark.Label(tx, "update_reporter_rep_ref"), refs[0].ResourceID, rec.ReporterType, rec.LocalResourceID, newReporterVersion).Error; err != nil {
+						return "", err
+					}
+					updatedReporter = true
+				}
+			}
+		}
+		path = benchmark.UpdatePath(updatedCommon, updatedReporter)
+	}
+
+	return path, nil
+}
+
+func updateCommonRepresentationVersion(
+	tx *gorm.DB,
+	resourceID uuid.UUID,
+	newVersion int,
+) *gorm.DB {
+	return tx.Model(&models.RepresentationReference{}).
+		Where("resource_id = ? AND reporter_type = ?", resourceID, "inventory").
+		Update("representation_version", newVersion)
+}
+
+func updateReporterRepresentationVersion(
+	tx *gorm.DB,
+	resourceID uuid.UUID,
+	reporterType string,
+	localResourceID string,
+	newVersion int,
+) *gorm.DB {
+	return tx.Model(&models.RepresentationReference{}).
+		Where("resource_id = ? AND reporter_type = ? AND local_resource_id = ?", resourceID, reporterType, localResourceID).
+		Update("representation_version", newVersion)
+}
+
+func insertReporterRepresentation(tx *gorm.DB, reporterRep *models.ReporterRepresentation) *gorm.DB {
+	return tx.Create(reporterRep)
+}
+
+func insertCommonRepresentation(tx *gorm.DB, commonRep *models.CommonRepresentation) *gorm.DB {
+	return tx.Create(commonRep)
+}
+
+// selectRefsOption1 loads all references of the resource rec belongs to into refs.
+func selectRefsOption1(
+	tx *gorm.DB,
+	rec benchmark.InputRecord,
+	refs *[]models.RepresentationReference,
+) *gorm.DB {
+	return tx.
+		Table("representation_references_option1 AS r1").
+		Joins("JOIN representation_references_option1 AS r2 ON r1.resource_id = r2.resource_id").
+		Where("r1.local_resource_id = ? AND r1.reporter_type = ? AND r1.resource_type = ? AND r1.reporter_instance_id = ?",
+			rec.LocalResourceID, rec.ReporterType, rec.ResourceType, rec.ReporterInstanceID).
+		Select("r2.*").
+		Scan(refs)
+}
+
+func insertResource(tx *gorm.DB, resource models.Resource) *gorm.DB {
+	return tx.Create(&resource)
+}
+
+func insertRepresentationReferences(tx *gorm.DB, refsToCreate []models.RepresentationReference) *gorm.DB {
+	return tx.Create(&refsToCreate)
+}
@@ -0,0 +1,299 @@
+package options
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/yourusername/go-db-bench/benchmark"
+	option2models "github.com/yourusername/go-db-bench/db/schemas/option2_normalized_reference_2_rep_tables/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func init() {
+	benchmark.RegisterOption(option2{})
+}
+
+// option2 normalizes representation references into rows that point at the
+// common and reporter representation tables by ID.
+type option2 struct{}
+
+func (option2) Name() string { return "option2" }
+
+func (option2) Models() []interface{} {
+	return []interface{}{
+		&option2models.Resource{},
+		&option2models.CommonRepresentation{},
+		&option2models.ReporterRepresentation{},
+		&option2models.RepresentationReference{},
+	}
+}
+
+func (option2) ExtraDDL() []string {
+	return []string{
+		`
+	CREATE UNIQUE INDEX IF NOT EXISTS unique_resource_reporter_rep_idx
+	ON representation_reference_option2 (resource_id, reporter_representation_id)
+	WHERE reporter_representation_id IS NOT NULL;`,
+		`
+	CREATE UNIQUE INDEX IF NOT EXISTS unique_resource_common_rep_idx
+	ON representation_reference_option2 (resource_id, common_representation_id)
+	WHERE common_representation_id IS NOT NULL;`,
+	}
+}
+
+func (option2) ProcessRecord(tx *gorm.DB, rec benchmark.InputRecord) (benchmark.Path, error) {
+	return ProcessRecordOption2(tx, rec)
+}
+
+func ProcessRecordOption2(tx *gorm.DB, rec benchmark.InputRecord) (benchmark.Path, error) {
+	var refs []option2models.JoinedRepresentation
+
+	if err := selectRefsAndRepsOption2(benchmark.Label(tx, "select_refs_and_reps_join"), rec, &refs).Error; err != nil {
+		return "", err
+	}
+
+	if len(refs) == 0 {
+		return benchmark.PathCreate, CreateResourceAndRepresentationsOption2(tx, rec)
+	}
+
+	shouldInsertCommon := rec.Common != nil && len(rec.Common) > 0
+	shouldInsertReporter := rec.Reporter != nil && len(rec.Reporter) > 0
+	return benchmark.UpdatePath(shouldInsertCommon, shouldInsertReporter), updateResourceAndRepresentationsOption2(tx, rec, refs)
+}
+
+func CreateResourceAndRepresentationsOption2(tx *gorm.DB, rec benchmark.InputRecord) error {
+	//fmt.Println("Creating Resource and Representation")
+	resourceID := uuid.New()
+	res := option2models.Resource{ID: resourceID, Type: rec.ResourceType}
+
+	//fmt.Printf("Inserting Resource")
+	// Insert Resource
+	if err := insertResourceOption2(benchmark.Label(tx, "insert_resource"), res).Error; err != nil {
+		return err
+	}
+
+	// Prepare CommonRepresentation
+	commonRepresentationId := uuid.New()
+	commonData := prepareJSON(rec.Common, map[string]string{"workspaceId": "default"})
+	commonRep := &option2models.CommonRepresentation{
+		ID:              commonRepresentationId,
+		LocalResourceID: resourceID.String(),
+		Version:         1,
+		ResourceType:    rec.ResourceType,
+		BaseRepresentation: option2models.BaseRepresentation{
+			Data: commonData,
+		},
+		ReportedBy: rec.ReporterType,
+	}
+
+	if err := insertCommonRepresentationOption2(benchmark.Label(tx, "insert_common_rep"), commonRep).Error; err != nil {
+		return err
+	}
+
+	cv := 1
+	// Prepare ReporterRepresentation
+	reporterRepresentationId := uuid.New()
+	reporterData := prepareJSON(rec.Reporter, map[string]string{})
+	reporterRep := &option2models.ReporterRepresentation{
+		ID:                 reporterRepresentationId,
+		LocalResourceID:    rec.LocalResourceID,
+		ReporterType:       rec.ReporterType,
+		ResourceType:       rec.ResourceType,
+		Version:            1,
+		ReporterVersion:    rec.ReporterVersion,
+		ReporterInstanceID: rec.ReporterInstanceID,
+		APIHref:            rec.APIHref,
+		ConsoleHref:        rec.ConsoleHref,
+		CommonVersion:      &cv,
+		Tombstone:          rec.Tombstone,
+		Generation:         1,
+		BaseRepresentation: option2models.BaseRepresentation{
+			Data: reporterData,
+		},
+	}
+
+	if err := insertReporterRepresentationOption2(benchmark.Label(tx, "insert_reporter_rep"), reporterRep).Error; err != nil {
+		return err
+	}
+
+	// Insert representation_references for reporter_representation and common_representation
+	refs := []option2models.RepresentationReference{
+		{
+			ReporterRepresentationID: &reporterRepresentationId,
+			ResourceID:               resourceID,
+			CommonRepresentationID:   nil,
+		},
+		{
+			CommonRepresentationID:   &commonRepresentationId,
+			ResourceID:               resourceID,
+			ReporterRepresentationID: nil,
+		},
+	}
+	return insertRepresentationReferencesOption2(benchmark.Label(tx, "insert_rep_refs"), refs).Error
+}
+
+func updateResourceAndRepresentationsOption2(
+	tx *gorm.DB,
+	rec benchmark.InputRecord,
+	joinedReps []option2models.JoinedRepresentation,
+) error {
+	var (
+		commonVersion, reporterVersion, generation int
+		resourceID                                 = joinedReps[0].ResourceID
+	)
+
+	//fmt.Println("✅ Updating reps and refs", len(joinedReps))
+
+	// Determine latest versions
+	for _, joined := range joinedReps {
+		if joined.Reporter.ReporterType == "" || joined.Reporter.ReporterType == "inventory" {
+			commonVersion = joined.Common.Version
+		} else if joined.Reporter.ReporterType == rec.ReporterType {
+			reporterVersion = joined.Reporter.Version
+			generation = joined.Reporter.Generation
+		}
+	}
+
+	newReporterVersion := reporterVersion + 1
+	newCommonVersion := commonVersion + 1
+	newReporterID := uuid.New()
+	newCommonID := uuid.New()
+
+	shouldInsertCommon := rec.Common != nil && len(rec.Common) > 0
+	shouldInsertReporter := rec.Reporter != nil && len(rec.Reporter) > 0
+
+	// Insert new CommonRepresentation
+	if shouldInsertCommon {
+		//fmt.Println("✅ Inserting new common rep")
+		commonRep := &option2models.CommonRepresentation{
+			ID:              newCommonID,
+			LocalResourceID: resourceID.String(),
+			Version:         newCommonVersion,
+			ResourceType:    rec.ResourceType,
+			ReportedBy:      rec.ReporterType,
+			BaseRepresentation: option2models.BaseRepresentation{
+				Data: datatypes.JSON(rec.Common),
+			},
+		}
+		if err := insertCommonRepresentationOption2(benchmark.Label(tx, "insert_common_rep"), commonRep).Error; err != nil {
+			return err
+		}
+	}
+
+	// Insert new ReporterRepresentation
+	if shouldInsertReporter {
+		//fmt.Println("✅ Inserting new reporter rep")
+		var commonVersionPtr *int
+		if shouldInsertCommon {
+			commonVersionPtr = &newCommonVersion
+		}
+
+		reporterRep := &option2models.ReporterRepresentation{
+			ID:                 newReporterID,
+			LocalResourceID:    rec.LocalResourceID,
+			ReporterType:       rec.ReporterType,
+			ResourceType:       rec.ResourceType,
+			Version:            newReporterVersion,
+			ReporterVersion:    rec.ReporterVersion,
+			ReporterInstanceID: rec.ReporterInstanceID,
+			APIHref:            rec.APIHref,
+			ConsoleHref:        rec.ConsoleHref,
+			CommonVersion:      commonVersionPtr,
+			Tombstone:          rec.Tombstone,
+			Generation:         generation,
+			BaseRepresentation: option2models.BaseRepresentation{
+				Data: datatypes.JSON(rec.Reporter),
+			},
+		}
+
+		if err := insertReporterRepresentationOption2(benchmark.Label(tx, "insert_reporter_rep"), reporterRep).Error; err != nil {
+			return err
+		}
+	}
+
+	// ✅ Update reference table rows individually
+	if shouldInsertCommon {
+		//fmt.Println("✅ Updating common rep")
+		err := benchmark.Label(tx, "update_ref_common").
+			Table("representation_reference_option2").
+			Where("resource_id = ?", resourceID).
+			Where("common_representation_id IS NOT NULL").
+			Update("common_representation_id", newCommonID).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if shouldInsertReporter {
+		//fmt.Println("✅ Updating reporter rep")
+		err := benchmark.Label(tx, "update_ref_reporter").
+			Table("representation_reference_option2").
+			Where("resource_id = ?", resourceID).
+			Where("reporter_representation_id IS NOT NULL").
+			Update("reporter_representation_id", newReporterID).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertRepresentationReferencesOption2(tx *gorm.DB, refs []option2models.RepresentationReference) *gorm.DB {
+	return tx.Create(&refs)
+}
+
+func insertCommonRepresentationOption2(tx *gorm.DB, rep *option2models.CommonRepresentation) *gorm.DB {
+	return tx.Create(rep)
+}
+
+func insertReporterRepresentationOption2(tx *gorm.DB, rep *option2models.ReporterRepresentation) *gorm.DB {
+	return tx.Create(rep)
+}
+
+func insertResourceOption2(tx *gorm.DB, resource option2models.Resource) *gorm.DB {
+	return tx.Create(&resource)
+}
+
+// selectRefsAndRepsOption2 loads the references of the resource rec belongs to,
+// joined with their reporter and common representations, into results.
+func selectRefsAndRepsOption2(
+	tx *gorm.DB,
+	rec benchmark.InputRecord,
+	results *[]option2models.JoinedRepresentation,
+) *gorm.DB {
+	return tx.
+		Table("representation_reference_option2 AS ref").
+		Joins(`
+		LEFT JOIN reporter_representation_option2 AS rr 
+		ON rr.id = ref.reporter_representation_id 
+		AND rr.local_resource_id = ? 
+		AND rr.reporter_instance_id = ? 
+		AND rr.reporter_type = ? 
+		AND rr.resource_type = ?
+	`, rec.LocalResourceID, rec.ReporterInstanceID, rec.ReporterType, rec.ResourceType).
+		Joins(`
+		LEFT JOIN common_representation_option2 AS cr 
+		ON cr.id = ref.common_representation_id
+	`).
+		Select(`
+		ref.resource_id AS resource_id,
+		rr.id AS reporter_id,
+		rr.reporter_type AS reporter_reporter_type,
+		rr.version AS reporter_version,
+		rr.generation AS reporter_generation,
+		rr.common_version AS reporter_common_version,
+		rr.tombstone AS reporter_tombstone,
+		cr.version AS common_version,
+		cr.reporter_type AS common_reporter_type
+	`).
+		Scan(results)
+}
+
+func prepareJSON(input json.RawMessage, defaultVal map[string]string) datatypes.JSON {
+	if len(input) == 0 {
+		b, _ := json.Marshal(defaultVal)
+		return b
+	}
+	return datatypes.JSON(input)
+}
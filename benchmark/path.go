@@ -0,0 +1,93 @@
+package benchmark
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/yourusername/go-db-bench/benchmark/input_files"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// Path is the branch an option took to apply a record.
+type Path string
+
+const (
+	// PathCreate created the resource and its first representations.
+	PathCreate Path = "create"
+	// PathUpdateCommon, PathUpdateReporter and PathUpdateBoth added a new
+	// version of the common representation, the reporter representation or
+	// both to an existing resource.
+	PathUpdateCommon   Path = "update-common"
+	PathUpdateReporter Path = "update-reporter"
+	PathUpdateBoth     Path = "update-both"
+	// PathUnchanged found the resource but had nothing to update.
+	PathUnchanged Path = "unchanged"
+)
+
+// UpdatePath returns the path of an update that wrote the common and/or the
+// reporter representation.
+func UpdatePath(common, reporter bool) Path {
+	switch {
+	case common && reporter:
+		return PathUpdateBoth
+	case common:
+		return PathUpdateCommon
+	case reporter:
+		return PathUpdateReporter
+	default:
+		return PathUnchanged
+	}
+}
+
+// Category is the workload category the generator assigned to the record's
+// resource. Inputs generated before categories were written to the records
+// fall back to the default workload, see input_files.Categorize; records
+// whose local resource ID is not a generated number then have no category.
+func Category(rec InputRecord) string {
+	if rec.Category != "" {
+		return rec.Category
+	}
+	id, err := strconv.ParseUint(rec.LocalResourceID, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return input_files.Categorize(id)
+}
+
+// payloadBuckets are the upper bounds of the payload size buckets. Postgres
+// compresses or moves values out of line once a row exceeds about 2KB, so the
+// first bucket holds the payloads that stay inline.
+var payloadBuckets = []struct {
+	limit int
+	name  string
+}{
+	{2 << 10, "<2KB"},
+	{8 << 10, "2-8KB"},
+	{32 << 10, "8-32KB"},
+	{128 << 10, "32-128KB"},
+	{1 << 20, "128KB-1MB"},
+	{math.MaxInt, ">=1MB"},
+}
+
+// PayloadBucket names the size bucket of a record whose reporter and common
+// JSON take bytes.
+func PayloadBucket(bytes int) string {
+	for _, b := range payloadBuckets {
+		if bytes < b.limit {
+			return b.name
+		}
+	}
+	return payloadBuckets[len(payloadBuckets)-1].name
+}
+
+// payloadBucketNames returns the buckets present in histograms from small to
+// large.
+func payloadBucketNames(histograms map[string]*stats.Histogram) []string {
+	var names []string
+	for _, b := range payloadBuckets {
+		if histograms[b.name] != nil {
+			names = append(names, b.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,185 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// Shape renders the structure of the plan: node types, join types, relations
+// and indexes, without costs, row counts or timings. Plans with the same shape
+// were executed the same way.
+func (n *PlanNode) Shape() string {
+	var b strings.Builder
+	n.writeShape(&b)
+	return b.String()
+}
+
+func (n *PlanNode) writeShape(b *strings.Builder) {
+	b.WriteString(n.NodeType)
+	if n.JoinType != "" {
+		b.WriteString(" " + n.JoinType)
+	}
+	if n.RelationName != "" || n.IndexName != "" {
+		b.WriteString("[" + n.RelationName)
+		if n.IndexName != "" {
+			b.WriteString(":" + n.IndexName)
+		}
+		b.WriteString("]")
+	}
+	if len(n.Plans) > 0 {
+		b.WriteString("(")
+		for i := range n.Plans {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			n.Plans[i].writeShape(b)
+		}
+		b.WriteString(")")
+	}
+}
+
+// Fingerprint is a short hash of the plan shape.
+func (r *ExplainResult) Fingerprint() string {
+	h := fnv.New64a()
+	h.Write([]byte(r.Plan.Shape()))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// PlanChange marks the record at which the plan of a step label switched to a
+// different shape, with the step's median latency over the records that used
+// the previous and the new plan.
+type PlanChange struct {
+	Label           string
+	RecordIndex     int
+	FromFingerprint string
+	ToFingerprint   string
+	FromShape       string
+	ToShape         string
+	RecordsBefore   int
+	RecordsAfter    int
+	P50Before       time.Duration
+	P50After        time.Duration
+}
+
+// planSegment is a run of consecutive executions of a label with one plan.
+type planSegment struct {
+	start       int
+	fingerprint string
+	shape       string
+	durations   []time.Duration
+}
+
+// DetectPlanChanges walks the records in input order and reports every point
+// at which a step label's plan fingerprint changed. It needs the plans
+// recorded in explain mode; steps without a plan are ignored, and so are
+// warm-up records.
+func DetectPlanChanges(result RunResult) []PlanChange {
+	segments := map[string][]*planSegment{}
+	var labels []string
+
+	for _, rec := range result.MeasuredRecords() {
+		for _, step := range rec.Steps {
+			if step.Plan == nil {
+				continue
+			}
+			fingerprint := step.Plan.Fingerprint()
+			labelSegments := segments[step.Label]
+			if len(labelSegments) == 0 {
+				labels = append(labels, step.Label)
+			}
+			if len(labelSegments) == 0 || labelSegments[len(labelSegments)-1].fingerprint != fingerprint {
+				labelSegments = append(labelSegments, &planSegment{start: rec.Index, fingerprint: fingerprint, shape: step.Plan.Plan.Shape()})
+				segments[step.Label] = labelSegments
+			}
+			current := labelSegments[len(labelSegments)-1]
+			current.durations = append(current.durations, step.Duration)
+		}
+	}
+
+	var changes []PlanChange
+	for _, label := range labels {
+		labelSegments := segments[label]
+		for i := 1; i < len(labelSegments); i++ {
+			before, after := labelSegments[i-1], labelSegments[i]
+			changes = append(changes, PlanChange{
+				Label:           label,
+				RecordIndex:     after.start,
+				FromFingerprint: before.fingerprint,
+				ToFingerprint:   after.fingerprint,
+				FromShape:       before.shape,
+				ToShape:         after.shape,
+				RecordsBefore:   len(before.durations),
+				RecordsAfter:    len(after.durations),
+				P50Before:       medianDuration(before.durations),
+				P50After:        medianDuration(after.durations),
+			})
+		}
+	}
+	return changes
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	h := stats.NewHistogram()
+	for _, d := range durations {
+		h.Record(d)
+	}
+	return h.Percentile(50)
+}
+
+// PrintPlanChanges writes a human readable list of plan changes.
+func PrintPlanChanges(w io.Writer, run int, changes []PlanChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "🧭 Run %d: no plan changes\n", run)
+		return
+	}
+	fmt.Fprintf(w, "🧭 Run %d: %d plan changes\n", run, len(changes))
+	for _, c := range changes {
+		fmt.Fprintf(w, "  - %s at record %d: p50 %s -> %s\n      %s\n   -> %s\n",
+			c.Label, c.RecordIndex, c.P50Before, c.P50After, c.FromShape, c.ToShape)
+	}
+}
+
+// WriteCSVPlanChanges appends the plan changes of a run to a CSV file.
+func WriteCSVPlanChanges(run int, isolation IsolationLevel, changes []PlanChange, outputPath string) error {
+	file, writeHeader, err := openCSVForAppend(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if writeHeader {
+		header := []string{"run", "isolation_level", "step_label", "record_index", "records_before", "records_after", "p50_before_ms", "p50_after_ms", "from_fingerprint", "to_fingerprint", "from_shape", "to_shape"}
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	for _, c := range changes {
+		row := []string{
+			strconv.Itoa(run),
+			string(isolation),
+			c.Label,
+			strconv.Itoa(c.RecordIndex),
+			strconv.Itoa(c.RecordsBefore),
+			strconv.Itoa(c.RecordsAfter),
+			fmt.Sprintf("%.3f", c.P50Before.Seconds()*1000),
+			fmt.Sprintf("%.3f", c.P50After.Seconds()*1000),
+			c.FromFingerprint,
+			c.ToFingerprint,
+			c.FromShape,
+			c.ToShape,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
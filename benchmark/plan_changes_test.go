@@ -0,0 +1,126 @@
+package benchmark
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func scanPlan(t *testing.T, node string, cost float64) *ExplainResult {
+	t.Helper()
+	index := ""
+	if node != "Seq Scan" {
+		index = `, "Index Name": "resources_pkey"`
+	}
+	plan, err := ParseExplainPlan(fmt.Sprintf(`[{"Plan": {"Node Type": "Limit", "Total Cost": %[3]g, "Plan Rows": %[3]g,
+		"Plans": [{"Node Type": %[1]q, "Relation Name": "resources"%[2]s, "Total Cost": %[3]g, "Actual Rows": 1}]}}]`, node, index, cost))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestPlanShape(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *ExplainResult
+		same bool
+	}{
+		{"seq scan to index scan", scanPlan(t, "Seq Scan", 10), scanPlan(t, "Index Scan", 10), false},
+		{"index scan to index only scan", scanPlan(t, "Index Scan", 10), scanPlan(t, "Index Only Scan", 10), false},
+		{"cost and rows only", scanPlan(t, "Index Scan", 8.3), scanPlan(t, "Index Scan", 4210), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := tt.a.Plan.Shape() == tt.b.Plan.Shape(); same != tt.same {
+				t.Errorf("shapes %q and %q: same = %v, want %v", tt.a.Plan.Shape(), tt.b.Plan.Shape(), same, tt.same)
+			}
+			if same := tt.a.Fingerprint() == tt.b.Fingerprint(); same != tt.same {
+				t.Errorf("fingerprints %s and %s: same = %v, want %v", tt.a.Fingerprint(), tt.b.Fingerprint(), same, tt.same)
+			}
+		})
+	}
+	if got, want := scanPlan(t, "Index Scan", 1).Plan.Shape(), "Limit(Index Scan[resources:resources_pkey])"; got != want {
+		t.Errorf("Shape() = %q, want %q", got, want)
+	}
+}
+
+func TestDetectPlanChanges(t *testing.T) {
+	seq := func(cost float64) *ExplainResult { return scanPlan(t, "Seq Scan", cost) }
+	index := func(cost float64) *ExplainResult { return scanPlan(t, "Index Scan", cost) }
+
+	// run builds a run with one select step per plan, taking i+1 ms; the
+	// first warmup records are warm-up.
+	run := func(warmup int, plans ...*ExplainResult) RunResult {
+		result := RunResult{Warmup: Warmup{Records: warmup}}
+		for i, plan := range plans {
+			result.Records = append(result.Records, RecordResult{
+				Index:  i,
+				Warmup: i < warmup,
+				Steps:  []StepTiming{{Label: "select_resource", Duration: time.Duration(i+1) * time.Millisecond, Plan: plan}},
+			})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		result RunResult
+		want   []PlanChange
+	}{
+		{
+			name:   "one plan",
+			result: run(0, index(1), index(1), index(1)),
+		},
+		{
+			name:   "cost only",
+			result: run(0, index(8), index(80), index(8000)),
+		},
+		{
+			name:   "seq scan to index scan and back",
+			result: run(0, seq(1), seq(2), index(3), index(4), index(5), seq(6)),
+			want: []PlanChange{
+				{RecordIndex: 2, RecordsBefore: 2, RecordsAfter: 3, P50Before: time.Millisecond, P50After: 4 * time.Millisecond},
+				{RecordIndex: 5, RecordsBefore: 3, RecordsAfter: 1, P50Before: 4 * time.Millisecond, P50After: 6 * time.Millisecond},
+			},
+		},
+		{
+			name:   "change during warm-up",
+			result: run(2, seq(1), index(2), index(3), index(4)),
+		},
+		{
+			name:   "change after warm-up",
+			result: run(2, seq(1), index(2), seq(3), index(4)),
+			want: []PlanChange{
+				{RecordIndex: 3, RecordsBefore: 1, RecordsAfter: 1, P50Before: 3 * time.Millisecond, P50After: 4 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectPlanChanges(tt.result)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				c := got[i]
+				if c.Label != "select_resource" || c.FromShape == c.ToShape || c.FromFingerprint == c.ToFingerprint {
+					t.Errorf("change %d = %+v, want a select_resource change between shapes", i, c)
+				}
+				if c.RecordIndex != want.RecordIndex || c.RecordsBefore != want.RecordsBefore || c.RecordsAfter != want.RecordsAfter {
+					t.Errorf("change %d at record %d after %d, %d records, want at %d after %d, %d",
+						i, c.RecordIndex, c.RecordsBefore, c.RecordsAfter, want.RecordIndex, want.RecordsBefore, want.RecordsAfter)
+				}
+				if !roughly(c.P50Before, want.P50Before) || !roughly(c.P50After, want.P50After) {
+					t.Errorf("change %d p50 %s -> %s, want %s -> %s", i, c.P50Before, c.P50After, want.P50Before, want.P50After)
+				}
+			}
+		})
+	}
+}
+
+// roughly reports whether got is within the histograms' precision of want.
+func roughly(got, want time.Duration) bool {
+	diff := got - want
+	return diff >= -want/100 && diff <= want/100
+}
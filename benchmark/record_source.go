@@ -0,0 +1,101 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MaxInputLine is the longest input line, and so the largest record, a
+// RecordSource accepts.
+const MaxInputLine = 64 * 1024 * 1024
+
+// streamBuffer is the number of records read ahead of the workers.
+const streamBuffer = 1024
+
+// SourcedRecord is an input record with its position in the input: Index
+// counts records from zero and Line is the line it was read from.
+type SourcedRecord struct {
+	Index  int
+	Line   int
+	Record InputRecord
+}
+
+// RecordSource reads input records from a JSONL file one at a time, so runs
+// never hold the whole input in memory. Blank lines are skipped.
+type RecordSource struct {
+	path    string
+	file    *os.File
+	scanner *bufio.Scanner
+	line    int
+	index   int
+	err     error
+}
+
+// OpenRecordSource opens the JSONL input at path.
+func OpenRecordSource(path string) (*RecordSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxInputLine)
+	return &RecordSource{path: path, file: file, scanner: scanner}, nil
+}
+
+// Next reads the next record. It returns false at the end of the input or on
+// the first error, see Err.
+func (s *RecordSource) Next() (SourcedRecord, bool) {
+	if s.err != nil {
+		return SourcedRecord{}, false
+	}
+	for s.scanner.Scan() {
+		s.line++
+		if len(s.scanner.Bytes()) == 0 {
+			continue
+		}
+		rec := SourcedRecord{Index: s.index, Line: s.line}
+		if err := json.Unmarshal(s.scanner.Bytes(), &rec.Record); err != nil {
+			s.err = fmt.Errorf("%s:%d: %w", s.path, s.line, err)
+			return SourcedRecord{}, false
+		}
+		s.index++
+		return rec, true
+	}
+	if err := s.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("record longer than %d bytes: %w", MaxInputLine, err)
+		}
+		s.err = fmt.Errorf("%s:%d: %w", s.path, s.line+1, err)
+	}
+	return SourcedRecord{}, false
+}
+
+// Stream reads the remaining records in the background and sends them on the
+// returned channel, which is closed at the end of the input or on the first
+// error. Check Err once the channel is closed.
+func (s *RecordSource) Stream() <-chan SourcedRecord {
+	records := make(chan SourcedRecord, streamBuffer)
+	go func() {
+		defer close(records)
+		for {
+			rec, ok := s.Next()
+			if !ok {
+				return
+			}
+			records <- rec
+		}
+	}()
+	return records
+}
+
+// Err returns the first error met while reading, if any.
+func (s *RecordSource) Err() error {
+	return s.err
+}
+
+func (s *RecordSource) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeInput(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func inputLine(t *testing.T, id string, reporter string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"local_resource_id": id,
+		"reporter":          map[string]string{"padding": reporter},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func readAll(t *testing.T, path string) ([]SourcedRecord, error) {
+	t.Helper()
+	source, err := OpenRecordSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	var records []SourcedRecord
+	for rec := range source.Stream() {
+		records = append(records, rec)
+	}
+	return records, source.Err()
+}
+
+func TestRecordSourceLongLines(t *testing.T) {
+	// bufio.Scanner stops at 64KB lines by default.
+	long := strings.Repeat("x", 1<<20)
+	path := writeInput(t, inputLine(t, "a", "short"), "", inputLine(t, "b", long), inputLine(t, "c", "short"))
+
+	records, err := readAll(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+	for i, want := range []struct {
+		id   string
+		line int
+	}{{"a", 1}, {"b", 3}, {"c", 4}} {
+		rec := records[i]
+		if rec.Index != i || rec.Line != want.line || rec.Record.LocalResourceID != want.id {
+			t.Errorf("record %d = index %d, line %d, id %q; want index %d, line %d, id %q", i, rec.Index, rec.Line, rec.Record.LocalResourceID, i, want.line, want.id)
+		}
+	}
+	if got := len(records[1].Record.Reporter); got < len(long) {
+		t.Errorf("long reporter payload has %d bytes, want at least %d", got, len(long))
+	}
+}
+
+func TestRecordSourceErrorsNameTheLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"malformed", []string{inputLine(t, "a", "x"), "", inputLine(t, "b", "x"), `{"local_resource_id": `}, "input.jsonl:4: "},
+		{"wrong type", []string{inputLine(t, "a", "x"), `{"tombstone": "yes"}`}, "input.jsonl:2: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readAll(t, writeInput(t, tt.lines...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error %v, want one containing %q", err, tt.want)
+			}
+			if len(records) == 0 {
+				t.Error("records before the bad line were not read")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// recordSpool keeps the complete results of a run's records in a temporary
+// file while the run holds only their timings. The SQL, vars and plans of the
+// steps grow with the payloads, so holding them for every record would make
+// a run's memory grow with its input; they are streamed back to the store
+// and the per-record CSV once the run is over.
+type recordSpool struct {
+	file *os.File
+	out  *bufio.Writer
+	enc  *json.Encoder
+	// written is the size of the spool so far and spans the byte range of
+	// every record by input index; records arrive in completion order.
+	written int64
+	spans   []storeSpan
+}
+
+func newRecordSpool() (*recordSpool, error) {
+	file, err := os.CreateTemp("", "kessel-bench-records-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record spool: %w", err)
+	}
+	s := &recordSpool{file: file}
+	s.out = bufio.NewWriter(file)
+	s.enc = json.NewEncoder(countingWriter{s.out, &s.written})
+	return s, nil
+}
+
+// countingWriter adds the number of bytes written through it to n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// add spools rec in the form the results store keeps it.
+func (s *recordSpool) add(rec RecordResult) error {
+	start := s.written
+	if err := s.enc.Encode(newStoredRecord("", 0, "", rec)); err != nil {
+		return err
+	}
+	for len(s.spans) <= rec.Index {
+		s.spans = append(s.spans, storeSpan{})
+	}
+	s.spans[rec.Index] = storeSpan{start, s.written}
+	return nil
+}
+
+// each calls fn with every spooled record in input order.
+func (s *recordSpool) each(fn func(RecordResult) error) error {
+	if err := s.out.Flush(); err != nil {
+		return fmt.Errorf("failed to write record spool: %w", err)
+	}
+	for _, span := range s.spans {
+		if span.end == 0 {
+			// The run ended before this record was processed.
+			continue
+		}
+		// A decoder has no line length limit, unlike a scanner; spooled
+		// records can be larger than the input lines they came from.
+		var stored storedRecord
+		if err := json.NewDecoder(io.NewSectionReader(s.file, span.start, span.end-span.start)).Decode(&stored); err != nil {
+			return fmt.Errorf("failed to read record spool: %w", err)
+		}
+		if err := fn(stored.result()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the spool.
+func (s *recordSpool) Close() error {
+	err := s.file.Close()
+	if rmErr := os.Remove(s.file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// timings returns rec without the SQL, vars and raw plans of its steps,
+// which is what a run holds in memory. Parsed plans are kept for plan change
+// detection.
+func (rec RecordResult) timings() RecordResult {
+	steps := make([]StepTiming, len(rec.Steps))
+	for i, step := range rec.Steps {
+		steps[i] = StepTiming{Label: step.Label, Duration: step.Duration, Plan: step.Plan}
+	}
+	rec.Steps = steps
+	return rec
+}
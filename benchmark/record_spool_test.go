@@ -0,0 +1,138 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func spooledRecord(index int, d time.Duration) RecordResult {
+	payload := strings.Repeat("p", 10000)
+	return RecordResult{
+		Index:    index,
+		Duration: d,
+		Finished: time.Duration(index+1) * time.Millisecond,
+		Outcome:  OutcomeCommitted,
+		Path:     "create",
+		Steps: []StepTiming{
+			{Label: "select_resource", SQL: "SELECT * FROM resources WHERE id = $1", Vars: []interface{}{"id"}, Duration: d / 4},
+			{Label: "insert_resource", SQL: "INSERT INTO resources (data) VALUES ($1)", Vars: []interface{}{payload}, Duration: d / 2},
+		},
+	}
+}
+
+func TestWriteResultsFromSpool(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenResultsStore(filepath.Join(dir, "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	session := &Session{ID: "s1", Input: ""}
+	if err := store.StartSession(session); err != nil {
+		t.Fatal(err)
+	}
+	perRecord := filepath.Join(dir, "per_record.csv")
+	runner := NewRunner(RunnerConfig{
+		Store:            store,
+		SessionID:        session.ID,
+		PerRecordCSVPath: perRecord,
+		Warmup:           WarmupPolicy{Records: 2},
+		Log:              io.Discard,
+	})
+
+	spool, err := newRecordSpool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	// Records finish out of input order, as with concurrent workers; the
+	// slowest one, index 3, is measured.
+	durations := []time.Duration{50, 10, 12, 40, 11}
+	result := RunResult{Run: 1, Isolation: IsolationSerializable, Records: make([]RecordResult, len(durations)), TotalElapsed: time.Second}
+	for _, i := range []int{2, 0, 4, 1, 3} {
+		rec := spooledRecord(i, durations[i]*time.Millisecond)
+		if err := spool.add(rec); err != nil {
+			t.Fatal(err)
+		}
+		result.Records[i] = rec.timings()
+	}
+	for _, rec := range result.Records {
+		for _, step := range rec.Steps {
+			if step.SQL != "" || step.Vars != nil {
+				t.Fatalf("timings kept the SQL or vars of step %s", step.Label)
+			}
+		}
+	}
+	result.Warmup = runner.cfg.Warmup.apply(result.Records)
+
+	if err := runner.writeResults(&result, spool); err != nil {
+		t.Fatal(err)
+	}
+	if result.Summary.MaxStep.Label != "insert_resource" || !strings.HasPrefix(result.Summary.MaxStep.SQL, "INSERT") {
+		t.Errorf("slowest step = %s %q, want insert_resource with its SQL", result.Summary.MaxStep.Label, result.Summary.MaxStep.SQL)
+	}
+
+	sessions, err := ReadResultsStore(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions[0].Load(); err != nil {
+		t.Fatal(err)
+	}
+	stored := sessions[0].Runs[0]
+	if len(stored.Records) != len(durations) {
+		t.Fatalf("store has %d records, want %d", len(stored.Records), len(durations))
+	}
+	// The spool replays records in input order, so that is the order of the
+	// store too.
+	var order []int
+	err = sessions[0].EachRecord(0, func(rec RecordResult) error {
+		order = append(order, rec.Index)
+		wantWarmup := rec.Index < 2
+		if rec.Warmup != wantWarmup {
+			t.Errorf("stored record %d warmup = %v, want %v", rec.Index, rec.Warmup, wantWarmup)
+		}
+		if len(rec.Steps) != 2 || rec.Steps[1].SQL == "" || len(rec.Steps[1].Vars) != 1 {
+			t.Errorf("stored record %d lost its steps: %+v", rec.Index, rec.Steps)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("store has records in order %v, want input order", order)
+	}
+	if stored.Summary.Latency != result.Summary.Latency {
+		t.Errorf("stored latency %+v, run had %+v", stored.Summary.Latency, result.Summary.Latency)
+	}
+	if stored.Summary.MaxStep.SQL != result.Summary.MaxStep.SQL {
+		t.Errorf("stored slowest step SQL %q, run had %q", stored.Summary.MaxStep.SQL, result.Summary.MaxStep.SQL)
+	}
+
+	file, err := os.Open(perRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + 2*len(durations); len(rows) != want {
+		t.Fatalf("per-record CSV has %d rows, want %d", len(rows), want)
+	}
+	for i, row := range rows[1:] {
+		if want := strconv.Itoa(i / 2); row[2] != want {
+			t.Errorf("per-record CSV row %d is of record %s, want %s", i+1, row[2], want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package report renders stored benchmark results as a self-contained HTML
+// page. Charts are inline SVG, so the page needs neither scripts nor a CDN.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// SlowestRecords is the number of records listed in the slowest records table.
+const SlowestRecords = 20
+
+// indexBuckets is the number of points per line in the latency over record
+// index chart.
+const indexBuckets = 200
+
+// group is one series of the report: the runs of a session at one isolation
+// level.
+type group struct {
+	name    string
+	session *benchmark.StoredSession
+	runs    []benchmark.RunResult
+	// indexes are the indexes of runs in session.Runs.
+	indexes []int
+	merged  *stats.RunHistograms
+}
+
+func groupsOf(sessions []*benchmark.StoredSession) []*group {
+	var groups []*group
+	for _, s := range sessions {
+		byLevel := map[benchmark.IsolationLevel]*group{}
+		for i, run := range s.Runs {
+			g, ok := byLevel[run.Isolation]
+			if !ok {
+				g = &group{session: s}
+				g.name = fmt.Sprintf("%s:%s %s", s.Option, s.Tag, run.Isolation)
+				byLevel[run.Isolation] = g
+				groups = append(groups, g)
+			}
+			g.runs = append(g.runs, run)
+			g.indexes = append(g.indexes, i)
+		}
+	}
+	for _, g := range groups {
+		runs := make([]*stats.RunHistograms, len(g.runs))
+		for i, run := range g.runs {
+			runs[i] = run.Summary.Histograms
+		}
+		g.merged = stats.MergeRuns(runs)
+	}
+	return groups
+}
+
+type page struct {
+	Generated string
+	Sessions  []sessionRow
+	CDF       template.HTML
+	Steps     template.HTML
+	ByIndex   template.HTML
+	Slowest   []slowRecord
+}
+
+type sessionRow struct {
+	Group      string
+	Session    string
+	Commit     string
+	Postgres   string
+	Runs       int
+	Records    int64
+	Throughput string
+	P50, P99   time.Duration
+	Max        time.Duration
+}
+
+type slowRecord struct {
+	Group    string
+	Run      int
+	Index    int
+	Duration time.Duration
+	Path     benchmark.Path
+	Step     string
+	StepTime time.Duration
+	SQL      string
+	Explain  string
+	Error    string
+}
+
+// Render writes the report on sessions, loaded with Load, to w.
+func Render(w io.Writer, sessions []*benchmark.StoredSession) error {
+	groups := groupsOf(sessions)
+	if len(groups) == 0 {
+		return fmt.Errorf("no runs to report on")
+	}
+	slowestRecords, err := slowest(groups, SlowestRecords)
+	if err != nil {
+		return err
+	}
+
+	p := page{
+		Generated: time.Now().Format(time.DateTime),
+		CDF:       cdfChart(groups),
+		Steps:     stepChart(groups),
+		ByIndex:   indexChart(groups),
+		Slowest:   slowestRecords,
+	}
+	for _, g := range groups {
+		s := g.merged.Records.Summary()
+		commit := g.session.GitCommit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if g.session.GitDirty {
+			commit += "+dirty"
+		}
+		p.Sessions = append(p.Sessions, sessionRow{
+			Group:      g.name,
+			Session:    g.session.ID,
+			Commit:     commit,
+			Postgres:   postgresVersion(g.session.Environment.Postgres.Version),
+			Runs:       len(g.runs),
+			Records:    s.Count,
+			Throughput: fmt.Sprintf("%.1f", g.merged.Throughput()),
+			P50:        s.P50,
+			P99:        s.P99,
+			Max:        s.Max,
+		})
+	}
+	return pageTemplate.Execute(w, p)
+}
+
+// postgresVersion shortens the output of version() to "PostgreSQL 16.2".
+func postgresVersion(version string) string {
+	fields := strings.Fields(version)
+	if len(fields) < 2 {
+		return version
+	}
+	return fields[0] + " " + fields[1]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// cdfChart plots the per-record latency distribution of every group.
+func cdfChart(groups []*group) template.HTML {
+	percentiles := []float64{}
+	for i := 0; i <= 100; i++ {
+		percentiles = append(percentiles, float64(i))
+	}
+	percentiles = append(percentiles[:99], 99, 99.5, 99.9, 99.99, 100)
+
+	x := axis{label: "latency", log: true, min: math.Inf(1), format: formatMillis}
+	y := axis{label: "percentile", min: 0, max: 100, format: func(v float64) string { return fmt.Sprintf("%g", v) }}
+	var lines []line
+	for i, g := range groups {
+		if g.merged.Records.Count() == 0 {
+			continue
+		}
+		l := line{name: g.name, color: color(i)}
+		for _, p := range percentiles {
+			ms := millis(g.merged.Records.Percentile(p))
+			l.points = append(l.points, [2]float64{ms, p})
+			if ms > 0 {
+				x.min = math.Min(x.min, ms)
+			}
+			x.max = math.Max(x.max, ms)
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	x.min, x.max = logBounds(x.min, x.max)
+	return lineChart(x, y, lines)
+}
+
+// stepChart shows how the mean time per record splits into steps.
+func stepChart(groups []*group) template.HTML {
+	var labels []string
+	seen := map[string]bool{}
+	for _, g := range groups {
+		for _, s := range benchmark.StepBreakdown(g.merged) {
+			if !seen[s.Label] {
+				seen[s.Label] = true
+				labels = append(labels, s.Label)
+			}
+		}
+	}
+
+	var bars []bar
+	for _, g := range groups {
+		records := g.merged.Records.Count()
+		if records == 0 {
+			continue
+		}
+		b := bar{name: g.name, values: make([]float64, len(labels))}
+		for _, s := range benchmark.StepBreakdown(g.merged) {
+			for i, label := range labels {
+				if label == s.Label {
+					b.values[i] = millis(s.Total) / float64(records)
+				}
+			}
+		}
+		bars = append(bars, b)
+	}
+	if len(bars) == 0 {
+		return ""
+	}
+	return stackedBars(labels, bars, "ms/record")
+}
+
+// indexChart plots p50 and p99 of the records of every group, bucketed by
+// input index over all runs, to show how latency develops as tables grow.
+func indexChart(groups []*group) template.HTML {
+	x := axis{label: "record index", format: func(v float64) string { return fmt.Sprintf("%g", v) }}
+	y := axis{label: "latency", log: true, min: math.Inf(1), format: formatMillis}
+	var lines []line
+	for i, g := range groups {
+		n := 0
+		for _, run := range g.runs {
+			n = max(n, len(run.Records))
+		}
+		if n == 0 {
+			continue
+		}
+		size := (n + indexBuckets - 1) / indexBuckets
+		buckets := make([]*stats.Histogram, (n+size-1)/size)
+		for b := range buckets {
+			buckets[b] = stats.NewHistogram()
+		}
+		for _, run := range g.runs {
+			for _, rec := range run.Records {
+				buckets[rec.Index/size].Record(rec.Duration)
+			}
+		}
+
+		p50 := line{name: g.name + " p50", color: color(i)}
+		p99 := line{name: g.name + " p99", color: color(i), dashed: true}
+		for b, h := range buckets {
+			if h.Count() == 0 {
+				continue
+			}
+			mid := float64(b*size + size/2)
+			lo, hi := millis(h.Percentile(50)), millis(h.Percentile(99))
+			p50.points = append(p50.points, [2]float64{mid, lo})
+			p99.points = append(p99.points, [2]float64{mid, hi})
+			if lo > 0 {
+				y.min = math.Min(y.min, lo)
+			}
+			y.max = math.Max(y.max, hi)
+		}
+		x.max = math.Max(x.max, float64(n))
+		lines = append(lines, p50, p99)
+	}
+	if len(lines) == 0 || math.IsInf(y.min, 1) {
+		return ""
+	}
+	y.min, y.max = logBounds(y.min, y.max)
+	return lineChart(x, y, lines)
+}
+
+// slowest returns the n slowest records over all groups with their slowest
+// step. The SQL and plans of the steps are only in the store, so the records
+// are streamed from it, keeping no more than the n slowest so far.
+func slowest(groups []*group, n int) ([]slowRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var records []slowRecord
+	for _, g := range groups {
+		for i, run := range g.runs {
+			err := g.session.EachRecord(g.indexes[i], func(rec benchmark.RecordResult) error {
+				if len(records) == n && rec.Duration <= records[n-1].Duration {
+					return nil
+				}
+				r := slowRecord{Group: g.name, Run: run.Run, Index: rec.Index, Duration: rec.Duration, Path: rec.Path}
+				for _, step := range rec.Steps {
+					if step.Duration >= r.StepTime {
+						r.Step, r.StepTime, r.SQL, r.Explain = step.Label, step.Duration, step.SQL, step.Explain
+					}
+				}
+				if rec.Err != nil {
+					r.Error = rec.Err.Error()
+				}
+				at := sort.Search(len(records), func(j int) bool { return records[j].Duration < r.Duration })
+				records = append(records, slowRecord{})
+				copy(records[at+1:], records[at:])
+				records[at] = r
+				if len(records) > n {
+					records = records[:n]
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return records, nil
+}
+
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>kessel-bench report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; }
+table { border-collapse: collapse; font-size: 0.85em; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eee; vertical-align: top; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+pre { white-space: pre-wrap; font-size: 0.9em; margin: 0.3em 0; }
+.chart .plot { fill: none; stroke: #999; }
+.chart .grid { stroke: #eee; }
+.chart .tick, .chart .legend { font-size: 11px; fill: #444; }
+.chart .axis { font-size: 12px; fill: #222; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>kessel-bench report</h1>
+<p>Generated {{.Generated}}.</p>
+
+<h2>Sessions</h2>
+<table>
+<tr><th>Series</th><th>Session</th><th>Commit</th><th>Server</th><th>Runs</th><th>Records</th><th>Records/s</th><th>p50</th><th>p99</th><th>Max</th></tr>
+{{range .Sessions}}<tr><td>{{.Group}}</td><td>{{.Session}}</td><td>{{.Commit}}</td><td>{{.Postgres}}</td><td class="num">{{.Runs}}</td><td class="num">{{.Records}}</td><td class="num">{{.Throughput}}</td><td class="num">{{.P50}}</td><td class="num">{{.P99}}</td><td class="num">{{.Max}}</td></tr>
+{{end}}</table>
+
+<h2>Latency distribution</h2>
+<p>Per-record latency of all measured records, runs merged.</p>
+{{.CDF}}
+
+<h2>Time per step</h2>
+<p>Mean time per record spent in every step.</p>
+{{.Steps}}
+
+<h2>Latency over record index</h2>
+<p>p50 (solid) and p99 (dashed) of the records in every slice of the input, over all runs, including warm-up.</p>
+{{.ByIndex}}
+
+<h2>Slowest records</h2>
+<table>
+<tr><th>Series</th><th>Run</th><th>Index</th><th>Path</th><th>Duration</th><th>Slowest step</th></tr>
+{{range .Slowest}}<tr><td>{{.Group}}</td><td class="num">{{.Run}}</td><td class="num">{{.Index}}</td><td>{{.Path}}</td><td class="num">{{.Duration}}</td>
+<td>{{.Step}} ({{.StepTime}}){{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .SQL}}<details><summary>SQL</summary><pre>{{.SQL}}</pre></details>{{end}}
+{{if .Explain}}<details><summary>Plan</summary><pre>{{.Explain}}</pre></details>{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
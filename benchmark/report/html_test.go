@@ -0,0 +1,156 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+)
+
+const (
+	slowSQL  = "UPDATE resources SET data = $1 WHERE id = 17"
+	slowPlan = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "resources"}}]`
+)
+
+// syntheticStore writes two sessions of two runs each to a store and returns
+// them loaded. Record 17 of the second run of the first session is the
+// slowest of all, in its update step.
+func syntheticStore(t *testing.T) []*benchmark.StoredSession {
+	t.Helper()
+	store, err := benchmark.OpenResultsStore(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for s, tag := range []string{"before", "after"} {
+		session := &benchmark.Session{ID: tag, Option: "option1", Tag: tag, GitCommit: "0123456789abcdef"}
+		if err := store.StartSession(session); err != nil {
+			t.Fatal(err)
+		}
+		for run := 1; run <= 2; run++ {
+			result := benchmark.RunResult{Run: run, Isolation: benchmark.IsolationSerializable, Concurrency: 1, TotalElapsed: time.Second}
+			for i := 0; i < 30; i++ {
+				d := time.Duration(1+i%7+s) * time.Millisecond
+				rec := benchmark.RecordResult{
+					Index:    i,
+					Duration: d,
+					Finished: time.Duration(i+1) * 10 * time.Millisecond,
+					Outcome:  benchmark.OutcomeCommitted,
+					Path:     "update",
+					Steps: []benchmark.StepTiming{
+						{Label: "select_resource", SQL: "SELECT * FROM resources WHERE id = $1", Duration: d / 3},
+						{Label: "update_resource", SQL: "UPDATE resources SET data = $1 WHERE id = $2", Duration: d / 2},
+					},
+				}
+				if s == 0 && run == 2 && i == 17 {
+					rec.Duration = 500 * time.Millisecond
+					rec.Steps[1] = benchmark.StepTiming{Label: "update_resource", SQL: slowSQL, Explain: slowPlan, Duration: 400 * time.Millisecond}
+				}
+				result.Records = append(result.Records, rec)
+			}
+			result.Summary = benchmark.AnalyzeRun(io.Discard, result)
+			if err := store.WriteRun(session.ID, result); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	sessions, err := benchmark.ReadResultsStore(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		if err := s.Load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sessions
+}
+
+func TestRender(t *testing.T) {
+	var out bytes.Buffer
+	if err := Render(&out, syntheticStore(t)); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+
+	for _, want := range []string{"option1:before serializable", "option1:after serializable", "0123456789ab"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report does not mention %q", want)
+		}
+	}
+
+	_, table, ok := strings.Cut(page, "<h2>Slowest records</h2>")
+	if !ok {
+		t.Fatal("report has no slowest records table")
+	}
+	table, _, _ = strings.Cut(table, "</table>")
+	rows := strings.Split(table, "<tr><td>")[1:]
+	if len(rows) != SlowestRecords {
+		t.Fatalf("slowest records table has %d rows, want %d", len(rows), SlowestRecords)
+	}
+	first := rows[0]
+	for _, want := range []string{">17<", "update_resource", template.HTMLEscapeString(slowSQL), template.HTMLEscapeString(slowPlan)} {
+		if !strings.Contains(first, want) {
+			t.Errorf("slowest record row does not contain %q: %s", want, first)
+		}
+	}
+	for i, row := range rows {
+		if !strings.Contains(row, "<summary>SQL</summary>") {
+			t.Errorf("slowest records row %d has no SQL", i)
+		}
+	}
+
+	svgs := regexp.MustCompile(`(?s)<svg .*?</svg>`).FindAllString(page, -1)
+	if len(svgs) != 3 {
+		t.Fatalf("report has %d charts, want 3: latency CDF, steps and latency by index", len(svgs))
+	}
+	for i, svg := range svgs {
+		if err := checkXML(svg); err != nil {
+			t.Errorf("chart %d is not well-formed: %v", i, err)
+		}
+	}
+}
+
+// checkXML reports whether doc is a single well-formed XML element.
+func checkXML(doc string) error {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if depth != 0 || roots != 1 {
+		return errors.New("not a single closed element")
+	}
+	return nil
+}
+
+func TestRenderNoRuns(t *testing.T) {
+	if err := Render(io.Discard, nil); err == nil {
+		t.Error("rendering no sessions succeeded, want an error")
+	}
+}
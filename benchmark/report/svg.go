@@ -0,0 +1,226 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// palette colours the series of a chart in order.
+var palette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+func color(i int) string {
+	return palette[i%len(palette)]
+}
+
+const (
+	chartWidth  = 900
+	chartHeight = 380
+	marginLeft  = 70
+	marginRight = 20
+	marginTop   = 20
+	marginBot   = 50
+)
+
+// axis maps data values onto one dimension of a chart.
+type axis struct {
+	label    string
+	min, max float64
+	log      bool
+	format   func(float64) string
+}
+
+// pos returns where v falls on an axis of the given length, 0 at min.
+func (a axis) pos(v float64, length float64) float64 {
+	lo, hi := a.min, a.max
+	if a.log {
+		v, lo, hi = math.Log10(math.Max(v, a.min)), math.Log10(lo), math.Log10(hi)
+	}
+	if hi == lo {
+		return 0
+	}
+	return (v - lo) / (hi - lo) * length
+}
+
+// ticks returns the values to label: powers of ten and their 2 and 5
+// multiples on a log axis, round steps on a linear one.
+func (a axis) ticks() []float64 {
+	var ticks []float64
+	if a.log {
+		for exp := math.Floor(math.Log10(a.min)); math.Pow(10, exp) <= a.max; exp++ {
+			for _, m := range []float64{1, 2, 5} {
+				if v := m * math.Pow(10, exp); v >= a.min && v <= a.max {
+					ticks = append(ticks, v)
+				}
+			}
+		}
+		return ticks
+	}
+	step := niceStep((a.max - a.min) / 6)
+	for v := math.Ceil(a.min/step) * step; v <= a.max+step/1e6; v += step {
+		ticks = append(ticks, v)
+	}
+	return ticks
+}
+
+// logBounds widens lo and hi to the nearest 1, 2 or 5 times a power of ten,
+// so a log axis starts and ends on a tick.
+func logBounds(lo, hi float64) (float64, float64) {
+	floor := math.Pow(10, math.Floor(math.Log10(lo)))
+	for _, m := range []float64{5, 2, 1} {
+		if m*floor <= lo {
+			floor *= m
+			break
+		}
+	}
+	ceil := math.Pow(10, math.Floor(math.Log10(hi)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*ceil >= hi {
+			ceil *= m
+			break
+		}
+	}
+	return floor, ceil
+}
+
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// line is one series of a line chart.
+type line struct {
+	name   string
+	color  string
+	dashed bool
+	points [][2]float64
+}
+
+// lineChart draws lines over the x and y axes as an inline SVG.
+func lineChart(x, y axis, lines []line) template.HTML {
+	plotW := float64(chartWidth - marginLeft - marginRight)
+	plotH := float64(chartHeight - marginTop - marginBot)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" class="chart">`, chartWidth, chartHeight+20*((len(lines)+2)/3), chartWidth, chartHeight+20*((len(lines)+2)/3))
+	writeAxes(&b, x, y, plotW, plotH)
+
+	for _, l := range lines {
+		var pts []string
+		for _, p := range l.points {
+			if (x.log && p[0] <= 0) || (y.log && p[1] <= 0) {
+				continue
+			}
+			px := marginLeft + x.pos(p[0], plotW)
+			py := marginTop + plotH - y.pos(p[1], plotH)
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", px, py))
+		}
+		dash := ""
+		if l.dashed {
+			dash = ` stroke-dasharray="5,3"`
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5"%s points="%s"><title>%s</title></polyline>`, l.color, dash, strings.Join(pts, " "), html.EscapeString(l.name))
+	}
+
+	for i, l := range lines {
+		lx := marginLeft + float64(i%3)*plotW/3
+		ly := float64(chartHeight) + float64(i/3)*20
+		dash := ""
+		if l.dashed {
+			dash = ` stroke-dasharray="5,3"`
+		}
+		fmt.Fprintf(&b, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="%s" stroke-width="2"%s/>`, lx, ly-4, lx+18, ly-4, l.color, dash)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" class="legend">%s</text>`, lx+24, ly, html.EscapeString(l.name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func writeAxes(b *strings.Builder, x, y axis, plotW, plotH float64) {
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" class="plot"/>`, marginLeft, marginTop, plotW, plotH)
+	for _, v := range x.ticks() {
+		px := marginLeft + x.pos(v, plotW)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.0f" class="grid"/>`, px, marginTop, px, marginTop+plotH)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.0f" text-anchor="middle" class="tick">%s</text>`, px, marginTop+plotH+16, x.format(v))
+	}
+	for _, v := range y.ticks() {
+		py := marginTop + plotH - y.pos(v, plotH)
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%.0f" y2="%.1f" class="grid"/>`, marginLeft, py, marginLeft+plotW, py)
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" class="tick">%s</text>`, marginLeft-6, py+4, y.format(v))
+	}
+	fmt.Fprintf(b, `<text x="%.0f" y="%.0f" text-anchor="middle" class="axis">%s</text>`, marginLeft+plotW/2, marginTop+plotH+36, html.EscapeString(x.label))
+	fmt.Fprintf(b, `<text x="14" y="%.0f" text-anchor="middle" transform="rotate(-90 14 %.0f)" class="axis">%s</text>`, marginTop+plotH/2, marginTop+plotH/2, html.EscapeString(y.label))
+}
+
+// bar is one row of a stacked bar chart; Values line up with the chart's
+// segment names.
+type bar struct {
+	name   string
+	values []float64
+}
+
+// stackedBars draws one horizontal bar per row, split into segments, as an
+// inline SVG. Values are in the unit named by unit.
+func stackedBars(segments []string, bars []bar, unit string) template.HTML {
+	const rowH, labelW = 28, 240
+	plotW := float64(chartWidth - labelW - marginRight - 80)
+
+	total := 0.0
+	for _, b := range bars {
+		sum := 0.0
+		for _, v := range b.values {
+			sum += v
+		}
+		total = math.Max(total, sum)
+	}
+	if total == 0 {
+		total = 1
+	}
+
+	legendRows := (len(segments) + 3) / 4
+	height := marginTop + rowH*len(bars) + 20 + 20*legendRows
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" class="chart">`, chartWidth, height, chartWidth, height)
+	for row, br := range bars {
+		y := float64(marginTop + row*rowH)
+		fmt.Fprintf(&b, `<text x="%d" y="%.0f" text-anchor="end" class="tick">%s</text>`, labelW-8, y+rowH/2+4, html.EscapeString(br.name))
+		x, sum := float64(labelW), 0.0
+		for i, v := range br.values {
+			w := v / total * plotW
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.0f" width="%.1f" height="%d" fill="%s"><title>%s: %.3f %s</title></rect>`, x, y+4, w, rowH-8, color(i), html.EscapeString(segments[i]), v, unit)
+			x += w
+			sum += v
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.0f" class="tick">%.3f %s</text>`, x+6, y+rowH/2+4, sum, unit)
+	}
+	for i, name := range segments {
+		lx := float64(labelW) + float64(i%4)*plotW/4
+		ly := float64(marginTop+rowH*len(bars)+20) + float64(i/4)*20
+		fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="12" height="12" fill="%s"/>`, lx, ly-10, color(i))
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" class="legend">%s</text>`, lx+18, ly, html.EscapeString(name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func formatMillis(v float64) string {
+	switch {
+	case v >= 1000:
+		return fmt.Sprintf("%gs", v/1000)
+	case v >= 1:
+		return fmt.Sprintf("%gms", v)
+	default:
+		return fmt.Sprintf("%gµs", v*1000)
+	}
+}
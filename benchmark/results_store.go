@@ -0,0 +1,585 @@
+package benchmark
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// ResultsStore appends benchmark sessions, their runs and their records to a
+// JSONL file, one typed entry per line. The file is the source of truth;
+// CSVs are exported from it.
+type ResultsStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// Session describes one invocation of the benchmark.
+type Session struct {
+	ID          string        `json:"id"`
+	Started     time.Time     `json:"started"`
+	Option      string        `json:"option"`
+	Tag         string        `json:"tag"`
+	Input       string        `json:"input"`
+	InputSHA256 string        `json:"input_sha256"`
+	GitCommit   string        `json:"git_commit"`
+	GitDirty    bool          `json:"git_dirty"`
+	Host        string        `json:"host"`
+	Config      SessionConfig `json:"config"`
+	Environment Environment   `json:"environment"`
+}
+
+// SessionConfig is the part of RunnerConfig that influences the results.
+type SessionConfig struct {
+	RunCount       int              `json:"run_count"`
+	Explain        bool             `json:"explain"`
+	Concurrency    int              `json:"concurrency"`
+	Mode           DispatchMode     `json:"mode"`
+	MaxRetries     int              `json:"max_retries"`
+	InitialBackoff time.Duration    `json:"initial_backoff_ns"`
+	MaxBackoff     time.Duration    `json:"max_backoff_ns"`
+	Isolation      []IsolationLevel `json:"isolation"`
+	Warmup         WarmupPolicy     `json:"warmup"`
+	TimeSeries     TimeSeriesConfig `json:"time_series"`
+}
+
+// NewSession describes a session running cfg at the given isolation levels.
+func NewSession(cfg RunnerConfig, tag string, isolation []IsolationLevel) *Session {
+	session := &Session{
+		Tag:   tag,
+		Input: cfg.InputPath,
+		Config: SessionConfig{
+			RunCount:       cfg.RunCount,
+			Explain:        cfg.Explain,
+			Concurrency:    cfg.Concurrency,
+			Mode:           cfg.Mode,
+			MaxRetries:     cfg.Retry.MaxRetries,
+			InitialBackoff: cfg.Retry.InitialBackoff,
+			MaxBackoff:     cfg.Retry.MaxBackoff,
+			Isolation:      isolation,
+			Warmup:         cfg.Warmup,
+			TimeSeries:     cfg.TimeSeries,
+		},
+	}
+	if cfg.Option != nil {
+		session.Option = cfg.Option.Name()
+	}
+	return session
+}
+
+// storedRun is the summary of one run as kept in the store.
+type storedRun struct {
+	Session     string         `json:"session"`
+	Run         int            `json:"run"`
+	Isolation   IsolationLevel `json:"isolation"`
+	Concurrency int            `json:"concurrency"`
+	Elapsed     time.Duration  `json:"elapsed_ns"`
+	Records     int            `json:"records"`
+	Throughput  float64        `json:"throughput"`
+	Latency     stats.Summary  `json:"latency"`
+	Failures    int            `json:"failures"`
+	Retries     int            `json:"retries"`
+	Warmup      Warmup         `json:"warmup"`
+	Windows     []Window       `json:"windows,omitempty"`
+}
+
+type storedRecord struct {
+	Session    string         `json:"session"`
+	Run        int            `json:"run"`
+	Isolation  IsolationLevel `json:"isolation"`
+	Index      int            `json:"index"`
+	Worker     int            `json:"worker"`
+	Duration   time.Duration  `json:"duration_ns"`
+	Retries    int            `json:"retries"`
+	WastedTime time.Duration  `json:"wasted_ns"`
+	Outcome    Outcome        `json:"outcome"`
+	Path       Path           `json:"path"`
+	Category   string         `json:"category"`
+	Payload    int            `json:"payload_bytes,omitempty"`
+	Finished   time.Duration  `json:"finished_ns"`
+	Warmup     bool           `json:"warmup,omitempty"`
+	Steps      []storedStep   `json:"steps"`
+	Error      string         `json:"error,omitempty"`
+	SQLState   string         `json:"sqlstate,omitempty"`
+}
+
+type storedStep struct {
+	Label    string        `json:"label"`
+	SQL      string        `json:"sql"`
+	Vars     []interface{} `json:"vars,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Explain  string        `json:"explain,omitempty"`
+}
+
+// storeEntry is one line of the store; exactly one of its payloads is set,
+// as named by Type.
+type storeEntry struct {
+	Type    string        `json:"type"`
+	Session *Session      `json:"session,omitempty"`
+	Run     *storedRun    `json:"run,omitempty"`
+	Record  *storedRecord `json:"record,omitempty"`
+}
+
+// OpenResultsStore opens the store at path for appending, creating it if needed.
+func OpenResultsStore(path string) (*ResultsStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results store: %w", err)
+	}
+	return &ResultsStore{path: path, file: file}, nil
+}
+
+func (s *ResultsStore) Path() string { return s.path }
+
+func (s *ResultsStore) Close() error {
+	return s.file.Close()
+}
+
+func (s *ResultsStore) write(entries ...storeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := bufio.NewWriter(s.file)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write %s to results store: %w", entry.Type, err)
+		}
+	}
+	return w.Flush()
+}
+
+// StartSession fills in the ID, start time, input hash, git commit and host of
+// session unless they are set, and records it.
+func (s *ResultsStore) StartSession(session *Session) error {
+	if session.ID == "" {
+		session.ID = time.Now().UTC().Format("20060102T150405") + "-" + uuid.NewString()[:8]
+	}
+	if session.Started.IsZero() {
+		session.Started = time.Now().UTC()
+	}
+	if session.InputSHA256 == "" && session.Input != "" {
+		sum, err := FileSHA256(session.Input)
+		if err != nil {
+			return err
+		}
+		session.InputSHA256 = sum
+	}
+	if session.GitCommit == "" {
+		session.GitCommit, session.GitDirty = gitRevision()
+	}
+	if session.Host == "" {
+		session.Host, _ = os.Hostname()
+	}
+	return s.write(storeEntry{Type: "session", Session: session})
+}
+
+// WriteRun records result and all of its records under session.
+func (s *ResultsStore) WriteRun(session string, result RunResult) error {
+	w, err := s.StartRun(session, result)
+	if err != nil {
+		return err
+	}
+	for _, rec := range result.Records {
+		if err := w.Add(rec); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// StartRun records the summary of result under session and returns a writer
+// for its records, so they can be streamed to the store rather than held in
+// memory. The summary counts len(result.Records) records.
+func (s *ResultsStore) StartRun(session string, result RunResult) (*RunWriter, error) {
+	err := s.write(storeEntry{Type: "run", Run: &storedRun{
+		Session:     session,
+		Run:         result.Run,
+		Isolation:   result.Isolation,
+		Concurrency: result.Concurrency,
+		Elapsed:     result.TotalElapsed,
+		Records:     len(result.Records),
+		Throughput:  result.Summary.Throughput,
+		Latency:     result.Summary.Latency,
+		Failures:    len(result.Failures),
+		Retries:     result.Summary.Retries,
+		Warmup:      result.Warmup,
+		Windows:     result.Windows,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return &RunWriter{store: s, session: session, run: result.Run, isolation: result.Isolation}, nil
+}
+
+// runWriterBatch is the number of records a RunWriter writes at once.
+const runWriterBatch = 1024
+
+// RunWriter writes the records of one run to the store, see StartRun.
+type RunWriter struct {
+	store     *ResultsStore
+	session   string
+	run       int
+	isolation IsolationLevel
+	pending   []storeEntry
+}
+
+// Add records rec. Records are written in batches; Close writes the rest.
+func (w *RunWriter) Add(rec RecordResult) error {
+	w.pending = append(w.pending, storeEntry{Type: "record", Record: newStoredRecord(w.session, w.run, w.isolation, rec)})
+	if len(w.pending) < runWriterBatch {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *RunWriter) flush() error {
+	err := w.store.write(w.pending...)
+	w.pending = w.pending[:0]
+	return err
+}
+
+// Close writes the records added since the last batch.
+func (w *RunWriter) Close() error {
+	return w.flush()
+}
+
+func newStoredRecord(session string, run int, isolation IsolationLevel, rec RecordResult) *storedRecord {
+	stored := &storedRecord{
+		Session:    session,
+		Run:        run,
+		Isolation:  isolation,
+		Index:      rec.Index,
+		Worker:     rec.Worker,
+		Duration:   rec.Duration,
+		Retries:    rec.Retries,
+		WastedTime: rec.WastedTime,
+		Outcome:    rec.Outcome,
+		Path:       rec.Path,
+		Category:   rec.Category,
+		Payload:    rec.PayloadBytes,
+		Finished:   rec.Finished,
+		Warmup:     rec.Warmup,
+	}
+	if rec.Err != nil {
+		stored.Error = rec.Err.Error()
+		stored.SQLState = SQLState(rec.Err)
+	}
+	for _, step := range rec.Steps {
+		stored.Steps = append(stored.Steps, storedStep{
+			Label:    step.Label,
+			SQL:      step.SQL,
+			Vars:     step.Vars,
+			Duration: step.Duration,
+			Explain:  step.Explain,
+		})
+	}
+	return stored
+}
+
+// StoredSession is a session read back from the store with its runs. The
+// runs have no records until Load reads them.
+type StoredSession struct {
+	Session
+	Runs []RunResult
+
+	path string
+	// spans locates the records of each run in the store, see storeSpan.
+	spans [][]storeSpan
+}
+
+// storeSpan is a byte range of the store holding consecutive records of one
+// run. A run's records are written together, so it usually has one span.
+type storeSpan struct {
+	start, end int64
+}
+
+// storeIndexEntry is the part of a store entry ReadResultsStore needs. The
+// steps of records are skipped rather than decoded.
+type storeIndexEntry struct {
+	Type    string     `json:"type"`
+	Session *Session   `json:"session,omitempty"`
+	Run     *storedRun `json:"run,omitempty"`
+	Record  *struct {
+		Session   string         `json:"session"`
+		Run       int            `json:"run"`
+		Isolation IsolationLevel `json:"isolation"`
+	} `json:"record,omitempty"`
+}
+
+// ReadResultsStore reads the sessions in the store at path, in the order
+// they were started, with their runs but not their records. Records can
+// add up to far more than fits in memory, so they are read per session by
+// Load and EachRecord.
+func ReadResultsStore(path string) ([]*StoredSession, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var sessions []*StoredSession
+	byID := map[string]*StoredSession{}
+	runIndex := map[string]int{}
+	runKey := func(session string, run int, isolation IsolationLevel) string {
+		return fmt.Sprintf("%s/%d/%s", session, run, isolation)
+	}
+
+	// A decoder has no line length limit, unlike a scanner; stored records
+	// can be larger than the input lines they came from.
+	dec := json.NewDecoder(bufio.NewReader(file))
+	for {
+		start := dec.InputOffset()
+		var entry storeIndexEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry at byte %d: %w", path, start, err)
+		}
+
+		switch {
+		case entry.Session != nil:
+			s := &StoredSession{Session: *entry.Session, path: path}
+			sessions = append(sessions, s)
+			byID[s.ID] = s
+		case entry.Run != nil:
+			s, ok := byID[entry.Run.Session]
+			if !ok {
+				return nil, fmt.Errorf("%s: entry at byte %d: run of unknown session %q", path, start, entry.Run.Session)
+			}
+			runIndex[runKey(s.ID, entry.Run.Run, entry.Run.Isolation)] = len(s.Runs)
+			s.Runs = append(s.Runs, RunResult{
+				Run:          entry.Run.Run,
+				Isolation:    entry.Run.Isolation,
+				Concurrency:  entry.Run.Concurrency,
+				TotalElapsed: entry.Run.Elapsed,
+				Warmup:       entry.Run.Warmup,
+				Windows:      entry.Run.Windows,
+			})
+			s.spans = append(s.spans, nil)
+		case entry.Record != nil:
+			r := entry.Record
+			s, ok := byID[r.Session]
+			i, found := runIndex[runKey(r.Session, r.Run, r.Isolation)]
+			if !ok || !found {
+				return nil, fmt.Errorf("%s: entry at byte %d: record of unknown run %d of session %q", path, start, r.Run, r.Session)
+			}
+			end := dec.InputOffset()
+			if spans := s.spans[i]; len(spans) > 0 && spans[len(spans)-1].end == start {
+				spans[len(spans)-1].end = end
+			} else {
+				s.spans[i] = append(spans, storeSpan{start, end})
+			}
+		default:
+			return nil, fmt.Errorf("%s: entry at byte %d: unknown entry type %q", path, start, entry.Type)
+		}
+	}
+	return sessions, nil
+}
+
+// EachRecord calls fn with every stored record of the run at index i of
+// s.Runs, complete with SQL, vars and plans, in the order they were stored.
+func (s *StoredSession) EachRecord(i int, fn func(RecordResult) error) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	run := s.Runs[i]
+	for _, span := range s.spans[i] {
+		dec := json.NewDecoder(bufio.NewReader(io.NewSectionReader(file, span.start, span.end-span.start)))
+		for {
+			var entry storeEntry
+			err := dec.Decode(&entry)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%s: entry at byte %d: %w", s.path, span.start+dec.InputOffset(), err)
+			}
+			r := entry.Record
+			if r == nil || r.Session != s.ID || r.Run != run.Run || r.Isolation != run.Isolation {
+				return fmt.Errorf("%s: entry at byte %d is not a record of run %d of session %q", s.path, span.start+dec.InputOffset(), run.Run, s.ID)
+			}
+			if err := fn(r.result()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads the records of every run of s and recomputes the run summaries
+// and, for sessions run in explain mode, the plan changes. Runs keep only
+// the timings of their records, in input order, as a Runner's runs do; the
+// complete records are read with EachRecord.
+func (s *StoredSession) Load() error {
+	for i := range s.Runs {
+		run := &s.Runs[i]
+		run.Records, run.Failures = nil, nil
+		// slowest is the complete record Summary.MaxStep is taken from:
+		// the first, in input order, of the slowest measured records.
+		var slowest *RecordResult
+		err := s.EachRecord(i, func(rec RecordResult) error {
+			if rec.Index < 0 {
+				return fmt.Errorf("%s: record %d of run %d of session %q has a negative index", s.path, rec.Index, run.Run, s.ID)
+			}
+			if !rec.Warmup && (slowest == nil || rec.Duration > slowest.Duration ||
+				rec.Duration == slowest.Duration && rec.Index < slowest.Index) {
+				slowest = &rec
+			}
+			if rec.Err != nil {
+				run.Failures = append(run.Failures, &RecordError{Index: rec.Index, Err: rec.Err})
+			}
+			for len(run.Records) <= rec.Index {
+				run.Records = append(run.Records, RecordResult{})
+			}
+			run.Records[rec.Index] = rec.timings()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(run.Failures, func(a, b int) bool { return run.Failures[a].Index < run.Failures[b].Index })
+
+		run.Summary = AnalyzeRun(io.Discard, *run)
+		if slowest != nil {
+			run.Summary.restoreMaxStep(*slowest)
+		}
+		if s.Config.Explain {
+			run.PlanChanges = DetectPlanChanges(*run)
+		}
+	}
+	return nil
+}
+
+// result restores the RecordResult r was stored from. Errors keep their
+// message and SQLSTATE, so serialization failures are still told apart.
+func (r *storedRecord) result() RecordResult {
+	rec := RecordResult{
+		Index:        r.Index,
+		Worker:       r.Worker,
+		Duration:     r.Duration,
+		Retries:      r.Retries,
+		WastedTime:   r.WastedTime,
+		Outcome:      r.Outcome,
+		Path:         r.Path,
+		Category:     r.Category,
+		PayloadBytes: r.Payload,
+		Finished:     r.Finished,
+		Warmup:       r.Warmup,
+	}
+	if r.Error != "" {
+		rec.Err = &storedError{message: r.Error, sqlState: r.SQLState}
+	}
+	for _, step := range r.Steps {
+		timing := StepTiming{
+			Label:    step.Label,
+			SQL:      step.SQL,
+			Vars:     step.Vars,
+			Duration: step.Duration,
+			Explain:  step.Explain,
+		}
+		if step.Explain != "" && !strings.HasPrefix(step.Explain, "❌") {
+			timing.Plan, _ = ParseExplainPlan(step.Explain)
+		}
+		rec.Steps = append(rec.Steps, timing)
+	}
+	return rec
+}
+
+// storedError is an error read back from the store. It unwraps to a
+// pgconn.PgError carrying the original SQLSTATE, if there was one.
+type storedError struct {
+	message  string
+	sqlState string
+}
+
+func (e *storedError) Error() string { return e.message }
+
+func (e *storedError) Unwrap() error {
+	if e.sqlState == "" {
+		return nil
+	}
+	return &pgconn.PgError{Code: e.sqlState}
+}
+
+// FindSession returns the session with the given ID, the most recent one for
+// "latest", or the most recent one of an option and tag for "option:tag".
+func FindSession(sessions []*StoredSession, id string) (*StoredSession, error) {
+	if id == "latest" && len(sessions) > 0 {
+		return sessions[len(sessions)-1], nil
+	}
+	for _, s := range sessions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	if option, tag, ok := strings.Cut(id, ":"); ok {
+		for i := len(sessions) - 1; i >= 0; i-- {
+			if sessions[i].Option == option && sessions[i].Tag == tag {
+				return sessions[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no session %q", id)
+}
+
+// FileSHA256 returns the hex SHA-256 checksum of the file at path.
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gitRevision returns the commit the binary was built from, falling back to
+// asking git about the working directory for go run and go test.
+func gitRevision() (commit string, dirty bool) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				commit = setting.Value
+			case "vcs.modified":
+				dirty = setting.Value == "true"
+			}
+		}
+		if commit != "" {
+			return commit, dirty
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, err := exec.Command("git", "status", "--porcelain").Output()
+	return strings.TrimSpace(string(out)), err == nil && len(strings.TrimSpace(string(status))) > 0
+}
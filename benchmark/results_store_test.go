@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadResultsStoreOrdersRecords(t *testing.T) {
+	store, err := OpenResultsStore(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, id := range []string{"s1", "s2"} {
+		if err := store.StartSession(&Session{ID: id, Option: "option1", Tag: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two runs are written at once, their records interleaved and out of
+	// input order, as by an older version writing records as they finished.
+	run := RunResult{Run: 1, Isolation: IsolationSerializable, Records: make([]RecordResult, 5), TotalElapsed: time.Second}
+	w1, err := store.StartRun("s1", run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w2, err := store.StartRun("s2", run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := []int{2, 0, 4, 1, 3}
+	for _, i := range stored {
+		rec := spooledRecord(i, time.Duration(10+i)*time.Millisecond)
+		if i == 4 {
+			rec.Err = errors.New("failed")
+		}
+		for _, w := range []*RunWriter{w1, w2} {
+			if err := w.Add(rec); err != nil {
+				t.Fatal(err)
+			}
+			// Write every record on its own so the runs interleave.
+			if err := w.flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	sessions, err := ReadResultsStore(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("read %d sessions, want 2", len(sessions))
+	}
+	for _, s := range sessions {
+		if len(s.Runs) != 1 || s.Runs[0].Records != nil {
+			t.Fatalf("session %s has runs %+v before Load, want one without records", s.ID, s.Runs)
+		}
+	}
+
+	s1 := sessions[0]
+	if err := s1.Load(); err != nil {
+		t.Fatal(err)
+	}
+	records := s1.Runs[0].Records
+	if len(records) != 5 {
+		t.Fatalf("loaded %d records, want 5", len(records))
+	}
+	for i, rec := range records {
+		if rec.Index != i || rec.Duration != time.Duration(10+i)*time.Millisecond {
+			t.Errorf("record %d has index %d, duration %s", i, rec.Index, rec.Duration)
+		}
+	}
+	if failures := s1.Runs[0].Failures; len(failures) != 1 || failures[0].Index != 4 {
+		t.Errorf("failures %v, want record 4", failures)
+	}
+	summary := s1.Runs[0].Summary
+	if summary.RecordCount != 5 || summary.MaxStepRecord != 4 || summary.MaxStep.SQL == "" {
+		t.Errorf("summary counts %d records, slowest %d with SQL %q; want 5, 4 with its SQL", summary.RecordCount, summary.MaxStepRecord, summary.MaxStep.SQL)
+	}
+	if sessions[1].Runs[0].Records != nil {
+		t.Error("loading one session loaded the records of another")
+	}
+
+	var order []int
+	err = s1.EachRecord(0, func(rec RecordResult) error {
+		order = append(order, rec.Index)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, stored) {
+		t.Errorf("EachRecord gave records %v, want them as stored, %v", order, stored)
+	}
+}
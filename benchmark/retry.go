@@ -0,0 +1,45 @@
+package benchmark
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how transactions that fail with a serialization failure
+// or deadlock are retried. The zero value disables retries.
+type RetryPolicy struct {
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry. It doubles after every
+	// further attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy retries a conflicting transaction up to five times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 5 * time.Millisecond,
+	MaxBackoff:     200 * time.Millisecond,
+}
+
+// Outcome is the final state of a record after all attempts.
+type Outcome string
+
+const (
+	OutcomeCommitted        Outcome = "committed"
+	OutcomeFailed           Outcome = "failed"
+	OutcomeRetriesExhausted Outcome = "retries_exhausted"
+)
+
+// backoff returns the wait before the given retry (1-based), with full jitter
+// so that conflicting workers do not retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+	d := p.InitialBackoff << (retry - 1)
+	if p.MaxBackoff > 0 && (d > p.MaxBackoff || d <= 0) {
+		d = p.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
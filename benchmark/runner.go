@@ -0,0 +1,565 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+	"github.com/yourusername/go-db-bench/config"
+	"gorm.io/gorm"
+)
+
+// RunnerConfig describes a series of runs of one schema option over one input file.
+type RunnerConfig struct {
+	DB     config.DBConfig
+	Option Option
+	// Explain records an EXPLAIN ANALYZE plan for every step, see TimingPlugin.
+	Explain   bool
+	RunCount  int
+	InputPath string
+
+	// Concurrency is the number of workers processing records. Values below
+	// one are treated as one, which processes records strictly in order.
+	Concurrency int
+	// Mode decides how records are handed to the workers.
+	Mode DispatchMode
+	// Retry controls retries of serialization failures and deadlocks.
+	Retry RetryPolicy
+	// Isolation is the transaction isolation level. Defaults to serializable.
+	Isolation IsolationLevel
+	// Warmup decides which records at the start of a run are left out of its
+	// statistics.
+	Warmup WarmupPolicy
+
+	// PerRecordCSVPath and PerRunCSVPath are optional; results are only
+	// written to CSV when they are set.
+	PerRecordCSVPath string
+	PerRunCSVPath    string
+	// PlanChangesCSVPath receives the plan changes detected in explain mode.
+	PlanChangesCSVPath string
+	// StepStatsCSVPath receives the per-step latency breakdown of every run.
+	StepStatsCSVPath string
+	// PathStatsCSVPath receives the latency per record path and input category
+	// of every run.
+	PathStatsCSVPath string
+	// TimeSeries cuts every run into windows, which are appended to
+	// TimeSeriesCSVPath, if set, as soon as they close.
+	TimeSeries        TimeSeriesConfig
+	TimeSeriesCSVPath string
+	// HistogramsPath, when set, receives the latency histograms of every run
+	// as one JSON line per run, see stats.ReadJSONL.
+	HistogramsPath string
+
+	// Store, when set, receives every run and its records under SessionID.
+	Store     *ResultsStore
+	SessionID string
+
+	// Log receives progress output. Defaults to os.Stdout.
+	Log io.Writer
+}
+
+// DispatchMode decides how input records are spread over the workers of a run.
+type DispatchMode string
+
+const (
+	// DispatchShared lets every worker take the next record from a shared queue,
+	// so records for the same resource can be processed concurrently.
+	DispatchShared DispatchMode = "shared"
+	// DispatchPartitioned assigns all records for a resource to the same worker,
+	// preserving their order and keeping workers off each other's rows.
+	DispatchPartitioned DispatchMode = "partitioned"
+)
+
+// ParseDispatchMode validates a dispatch mode name.
+func ParseDispatchMode(name string) (DispatchMode, error) {
+	switch mode := DispatchMode(name); mode {
+	case DispatchShared, DispatchPartitioned:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown dispatch mode %q (want %s or %s)", name, DispatchShared, DispatchPartitioned)
+}
+
+// RecordError is a failure to process a single input record.
+type RecordError struct {
+	Index int
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d transaction failed: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// SerializationFailure reports whether the record lost a serialization
+// conflict against a concurrent transaction.
+func (e *RecordError) SerializationFailure() bool {
+	return IsSerializationFailure(e.Err)
+}
+
+// RecordResult is the outcome of processing a single input record.
+type RecordResult struct {
+	Index  int
+	Worker int
+	// Duration covers all attempts, including backoff between them.
+	Duration time.Duration
+	// Steps are the step timings of the last attempt.
+	Steps []StepTiming
+	Err   error
+
+	// Retries is the number of attempts after the first one and WastedTime the
+	// time spent in failed attempts and backing off.
+	Retries    int
+	WastedTime time.Duration
+	Outcome    Outcome
+
+	// Path is the branch the last attempt took and Category the Zipf
+	// category of the record's resource.
+	Path     Path
+	Category string
+	// PayloadBytes is the size of the record's reporter and common JSON.
+	PayloadBytes int
+
+	// Finished is when the record completed, relative to the start of the
+	// run. Warm-up records are left out of the run's statistics.
+	Finished time.Duration
+	Warmup   bool
+}
+
+// RunResult holds everything measured during one run over the input records.
+type RunResult struct {
+	Run          int
+	Isolation    IsolationLevel
+	Concurrency  int
+	TotalElapsed time.Duration
+	// Records are in input order. Runs returned by a Runner keep only their
+	// timings, without the SQL, vars and raw plans of their steps; those are
+	// in the results store and the per-record CSV.
+	Records  []RecordResult
+	Failures []*RecordError
+	Summary  RunSummary
+	// PlanChanges are only detected in explain mode.
+	PlanChanges []PlanChange
+	Warmup      Warmup
+	// Windows is the time series of the run, if one was recorded.
+	Windows []Window
+}
+
+// Durations returns the per-record durations in input order.
+func (r RunResult) Durations() []time.Duration {
+	durations := make([]time.Duration, len(r.Records))
+	for i, rec := range r.Records {
+		durations[i] = rec.Duration
+	}
+	return durations
+}
+
+// MeasuredRecords returns the records outside the warm-up, in input order.
+func (r RunResult) MeasuredRecords() []RecordResult {
+	if r.Warmup.Records == 0 {
+		return r.Records
+	}
+	measured := make([]RecordResult, 0, len(r.Records)-r.Warmup.Records)
+	for _, rec := range r.Records {
+		if !rec.Warmup {
+			measured = append(measured, rec)
+		}
+	}
+	return measured
+}
+
+// StepTimings returns the per-record step timings in input order.
+func (r RunResult) StepTimings() [][]StepTiming {
+	timings := make([][]StepTiming, len(r.Records))
+	for i, rec := range r.Records {
+		timings[i] = rec.Steps
+	}
+	return timings
+}
+
+// Runner executes the benchmark runs described by a RunnerConfig. It reports
+// problems as errors rather than through a test so it can be embedded in the
+// kessel-bench command, a service or a Go benchmark.
+type Runner struct {
+	cfg RunnerConfig
+}
+
+func NewRunner(cfg RunnerConfig) *Runner {
+	if cfg.Log == nil {
+		cfg.Log = os.Stdout
+	}
+	return &Runner{cfg: cfg}
+}
+
+// Run executes all configured runs. Per-record failures do not stop a run;
+// they are collected in RunResult.Failures. The returned error is set when a
+// run could not be carried out at all, in which case the results of the runs
+// completed so far are returned alongside it.
+func (r *Runner) Run() ([]RunResult, error) {
+	if r.cfg.Option == nil {
+		return nil, fmt.Errorf("no schema option configured")
+	}
+
+	var results []RunResult
+	for run := 1; run <= r.cfg.RunCount; run++ {
+		fmt.Fprintf(r.cfg.Log, "\n🔁 Starting run %d/%d\n", run, r.cfg.RunCount)
+
+		// 1. Open the input; records are streamed to the workers as the run goes
+		fmt.Fprintf(r.cfg.Log, "\n🔁 Streaming records from %s", r.cfg.InputPath)
+		source, err := OpenRecordSource(r.cfg.InputPath)
+		if err != nil {
+			return results, fmt.Errorf("failed to open input records: %w", err)
+		}
+
+		//2. Timed: Execute the transaction, capture times for processing per record, times per SQL stmt, total time to process all records
+		spool, err := newRecordSpool()
+		if err != nil {
+			source.Close()
+			return results, err
+		}
+		result, err := r.executeRun(run, source, spool)
+		source.Close()
+		if err != nil {
+			spool.Close()
+			return results, fmt.Errorf("run %d: %w", run, err)
+		}
+
+		//3. Analyze the run and write its results
+		err = r.writeResults(&result, spool)
+		spool.Close()
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// writeResults analyzes result and writes it to the configured outputs. The
+// complete records are streamed from spool to the store and the per-record
+// CSV.
+func (r *Runner) writeResults(result *RunResult, spool *recordSpool) error {
+	run := result.Run
+	result.Summary = AnalyzeRun(r.cfg.Log, *result)
+	if r.cfg.HistogramsPath != "" {
+		if err := stats.AppendJSONL(r.cfg.HistogramsPath, result.Summary.Histograms); err != nil {
+			return fmt.Errorf("failed to write histograms: %w", err)
+		}
+	}
+	if r.cfg.StepStatsCSVPath != "" {
+		if err := WriteCSVStepStats(strconv.Itoa(run), result.Isolation, result.Summary.Steps, r.cfg.StepStatsCSVPath); err != nil {
+			return fmt.Errorf("failed to write CSV for steps: %w", err)
+		}
+	}
+	if r.cfg.PathStatsCSVPath != "" {
+		if err := WriteCSVPathStats(strconv.Itoa(run), result.Isolation, result.Summary.Histograms, r.cfg.PathStatsCSVPath); err != nil {
+			return fmt.Errorf("failed to write CSV for paths: %w", err)
+		}
+	}
+	if r.cfg.Explain {
+		result.PlanChanges = DetectPlanChanges(*result)
+		PrintPlanChanges(r.cfg.Log, run, result.PlanChanges)
+		if r.cfg.PlanChangesCSVPath != "" {
+			if err := WriteCSVPlanChanges(run, result.Isolation, result.PlanChanges, r.cfg.PlanChangesCSVPath); err != nil {
+				return fmt.Errorf("failed to write CSV for plan changes: %w", err)
+			}
+		}
+	}
+
+	if err := r.writeRecords(result, spool); err != nil {
+		return err
+	}
+
+	// write aggregated records to csv
+	if r.cfg.PerRunCSVPath != "" {
+		if err := WriteCSVForRun(*result, r.cfg.PerRunCSVPath); err != nil {
+			return fmt.Errorf("failed to write CSV for run: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeRecords streams the spooled records of result to the store and the
+// per-record CSV, marking the warm-up records. It also restores the SQL and
+// plan of the summary's slowest step, which the records in memory lack.
+func (r *Runner) writeRecords(result *RunResult, spool *recordSpool) error {
+	var stored *RunWriter
+	if r.cfg.Store != nil {
+		var err error
+		if stored, err = r.cfg.Store.StartRun(r.cfg.SessionID, *result); err != nil {
+			return err
+		}
+	}
+	var perRecord *recordsCSV
+	if r.cfg.PerRecordCSVPath != "" {
+		var err error
+		if perRecord, err = openRecordsCSV(r.cfg.PerRecordCSVPath); err != nil {
+			return fmt.Errorf("failed to write CSV for all records: %w", err)
+		}
+		defer perRecord.Close()
+	}
+
+	err := spool.each(func(rec RecordResult) error {
+		rec.Warmup = result.Records[rec.Index].Warmup
+		result.Summary.restoreMaxStep(rec)
+		if stored != nil {
+			if err := stored.Add(rec); err != nil {
+				return err
+			}
+		}
+		if perRecord != nil {
+			if err := perRecord.write(*result, rec); err != nil {
+				return fmt.Errorf("failed to write CSV for all records: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if stored != nil {
+		if err := stored.Close(); err != nil {
+			return err
+		}
+	}
+	if perRecord != nil {
+		if err := perRecord.Close(); err != nil {
+			return fmt.Errorf("failed to write CSV for all records: %w", err)
+		}
+	}
+	return nil
+}
+
+// executeRun recreates the database, migrates the configured option and
+// processes every record of source in its own transaction at the configured
+// isolation level, spread over the configured number of workers. Records are
+// read as the workers take them; an unreadable record ends the run with an
+// error once the records before it are processed. The result holds the
+// timings of the records, see RecordResult.timings; the complete records go
+// to spool.
+func (r *Runner) executeRun(run int, source *RecordSource, spool *recordSpool) (RunResult, error) {
+	result := RunResult{Run: run, Isolation: r.cfg.Isolation.orDefault()}
+
+	if err := config.DropAndRecreateDatabase(r.cfg.DB); err != nil {
+		return result, fmt.Errorf("failed to reset DB: %w", err)
+	}
+
+	db, err := config.OpenDB(r.cfg.DB)
+	if err != nil {
+		return result, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return result, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	defer sqlDB.Close()
+
+	fmt.Fprintf(r.cfg.Log, "\n🔁 Migrating %s", r.cfg.Option.Name())
+	if err := MigrateOption(db, r.cfg.Option); err != nil {
+		return result, fmt.Errorf("failed to migrate: %w", err)
+	}
+	if err := db.Use(TimingPlugin{}); err != nil {
+		return result, fmt.Errorf("failed to install timing plugin: %w", err)
+	}
+
+	workers := r.cfg.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	result.Concurrency = workers
+	sqlDB.SetMaxOpenConns(workers)
+	sqlDB.SetMaxIdleConns(workers)
+
+	// Table sizes are queried on their own connection so the workers never
+	// wait for one.
+	var statsDB *gorm.DB
+	if r.cfg.TimeSeries.enabled() {
+		statsDB, err = config.OpenDB(r.cfg.DB)
+		if err != nil {
+			return result, err
+		}
+		statsSQL, err := statsDB.DB()
+		if err != nil {
+			return result, fmt.Errorf("failed to get database handle: %w", err)
+		}
+		defer statsSQL.Close()
+		statsSQL.SetMaxOpenConns(1)
+	}
+
+	startTotal := time.Now()
+	var series *timeSeries
+	if statsDB != nil {
+		series = startTimeSeries(r.cfg.TimeSeries, statsDB, startTotal, func(w Window) error {
+			fmt.Fprintf(r.cfg.Log, "⏲️ %6s: %5d records, %8.1f records/s, p50 %s, p99 %s\n", w.End.Round(time.Second), w.Records, w.Throughput, w.P50, w.P99)
+			if r.cfg.TimeSeriesCSVPath == "" {
+				return nil
+			}
+			if err := WriteCSVWindows(run, result.Isolation, []Window{w}, r.cfg.TimeSeriesCSVPath); err != nil {
+				return fmt.Errorf("failed to write CSV for time series: %w", err)
+			}
+			return nil
+		})
+	}
+
+	// Workers hand their results to a collector, which spools them and files
+	// their timings by index as the number of records is only known at the
+	// end of the input.
+	done := make(chan RecordResult, workers)
+	collected := make(chan struct{})
+	var spoolErr error
+	go func() {
+		defer close(collected)
+		for rec := range done {
+			if spoolErr == nil {
+				spoolErr = spool.add(rec)
+			}
+			for len(result.Records) <= rec.Index {
+				result.Records = append(result.Records, RecordResult{})
+			}
+			result.Records[rec.Index] = rec.timings()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for worker, queue := range dispatch(source.Stream(), workers, r.cfg.Mode) {
+		wg.Add(1)
+		go func(worker int, queue <-chan SourcedRecord) {
+			defer wg.Done()
+			for rec := range queue {
+				res := r.processRecord(db, rec.Index, worker, rec.Record)
+				res.Finished = time.Since(startTotal)
+				series.observe(res)
+				done <- res
+			}
+		}(worker, queue)
+	}
+	wg.Wait()
+	result.TotalElapsed = time.Since(startTotal)
+	close(done)
+	<-collected
+	result.Windows, err = series.stop()
+	if err != nil {
+		return result, err
+	}
+	if spoolErr != nil {
+		return result, fmt.Errorf("failed to spool records: %w", spoolErr)
+	}
+	if err := source.Err(); err != nil {
+		return result, fmt.Errorf("failed to read input records: %w", err)
+	}
+	if series != nil && series.tablesErr != nil {
+		fmt.Fprintf(r.cfg.Log, "⚠️ Time series without table sizes: %v\n", series.tablesErr)
+	}
+	result.Warmup = r.cfg.Warmup.apply(result.Records)
+
+	for _, rec := range result.Records {
+		if rec.Err != nil {
+			result.Failures = append(result.Failures, &RecordError{Index: rec.Index, Err: rec.Err})
+		}
+	}
+	return result, nil
+}
+
+// processRecord runs the transaction for one record, retrying it according to
+// the retry policy while it fails with a retryable error. Records processed
+// under IsolationNone are never retried and count as failed.
+func (r *Runner) processRecord(db *gorm.DB, index, worker int, rec InputRecord) RecordResult {
+	result := RecordResult{Index: index, Worker: worker, Category: Category(rec), PayloadBytes: len(rec.Reporter) + len(rec.Common)}
+
+	start := time.Now()
+	for {
+		attemptStart := time.Now()
+		path, stepTimings, err := runInstrumentedTransaction(db, rec, r.cfg.Option, r.cfg.Explain, r.cfg.Isolation)
+		result.Path = path
+		result.Steps = stepTimings
+		result.Err = err
+
+		if err == nil {
+			result.Outcome = OutcomeCommitted
+			break
+		}
+		result.WastedTime += time.Since(attemptStart)
+		// Without a transaction the statements before the failing one are
+		// already committed, so a retry would apply them twice.
+		if !IsRetryable(err) || r.cfg.Isolation == IsolationNone {
+			result.Outcome = OutcomeFailed
+			break
+		}
+		if result.Retries >= r.cfg.Retry.MaxRetries {
+			result.Outcome = OutcomeRetriesExhausted
+			break
+		}
+
+		result.Retries++
+		wait := r.cfg.Retry.backoff(result.Retries)
+		time.Sleep(wait)
+		result.WastedTime += wait
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// dispatch spreads records over one queue per worker. In shared mode all
+// workers read from records directly.
+func dispatch(records <-chan SourcedRecord, workers int, mode DispatchMode) []<-chan SourcedRecord {
+	queues := make([]<-chan SourcedRecord, workers)
+	if mode != DispatchPartitioned {
+		for w := range queues {
+			queues[w] = records
+		}
+		return queues
+	}
+
+	partitions := make([]chan SourcedRecord, workers)
+	for w := range partitions {
+		partitions[w] = make(chan SourcedRecord, 64)
+		queues[w] = partitions[w]
+	}
+	go func() {
+		for rec := range records {
+			partitions[partitionOf(rec.Record, workers)] <- rec
+		}
+		for _, partition := range partitions {
+			close(partition)
+		}
+	}()
+	return queues
+}
+
+// partitionOf maps every record of a resource to the same worker.
+func partitionOf(rec InputRecord, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(rec.ResourceType + "/" + rec.ReporterType + "/" + rec.ReporterInstanceID + "/" + rec.LocalResourceID))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// runInstrumentedTransaction processes rec in a transaction at the given
+// isolation level, or statement by statement for IsolationNone, and returns the
+// path it took and the statements TimingPlugin recorded along the way.
+func runInstrumentedTransaction(db *gorm.DB, rec InputRecord, option Option, explain bool, isolation IsolationLevel) (Path, []StepTiming, error) {
+	recorder := &StepRecorder{}
+	db = db.WithContext(WithStepRecorder(WithExplain(context.Background(), explain), recorder))
+
+	txOptions := isolation.txOptions()
+	if txOptions == nil {
+		path, err := option.ProcessRecord(db, rec)
+		return path, recorder.Steps(), err
+	}
+
+	var path Path
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		path, err = option.ProcessRecord(tx, rec)
+		return err
+	}, txOptions)
+	return path, recorder.Steps(), err
+}
@@ -0,0 +1,207 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// MannWhitneyU tests whether a and b come from the same distribution. It
+// returns the U statistic of a and the two-sided p-value from the normal
+// approximation with tie and continuity correction, which is adequate from
+// about eight samples per side.
+func MannWhitneyU(a, b []float64) (u, p float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type sample struct {
+		value float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Tied values share the mean of the ranks they span.
+	var rankSumA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u = rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Erfc(z / math.Sqrt2)
+}
+
+// Interval is a point estimate with a confidence interval around it.
+type Interval struct {
+	Estimate float64
+	Low      float64
+	High     float64
+}
+
+// Excludes reports whether v lies outside the interval.
+func (i Interval) Excludes(v float64) bool {
+	return v < i.Low || v > i.High
+}
+
+// Bounded reports whether the interval was estimated at all; it is unbounded
+// when the samples were too small.
+func (i Interval) Bounded() bool {
+	return !math.IsInf(i.Low, 0) && !math.IsInf(i.High, 0)
+}
+
+func rankIndex(n int, q float64) int {
+	index := int(math.Ceil(q*float64(n))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= n {
+		index = n - 1
+	}
+	return index
+}
+
+// A Statistic summarizes a sample given as the sorted original values and how
+// often each of them occurs in the sample. Representing bootstrap resamples as
+// counts over the sorted original keeps them sorted without sorting them.
+type Statistic func(sorted []float64, counts []int) float64
+
+// Evaluate applies stat to xs.
+func Evaluate(stat Statistic, xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	return stat(sorted, ones(len(sorted)))
+}
+
+// QuantileStat returns the statistic of the q-quantile (0..1), by the
+// nearest-rank method.
+func QuantileStat(q float64) Statistic {
+	return func(sorted []float64, counts []int) float64 {
+		target := rankIndex(len(sorted), q) + 1
+		seen := 0
+		for i, c := range counts {
+			seen += c
+			if seen >= target {
+				return sorted[i]
+			}
+		}
+		return sorted[len(sorted)-1]
+	}
+}
+
+// MeanStat is the arithmetic mean.
+func MeanStat(sorted []float64, counts []int) float64 {
+	var sum float64
+	for i, c := range counts {
+		sum += sorted[i] * float64(c)
+	}
+	return sum / float64(len(sorted))
+}
+
+// MinBootstrapSamples is the smallest sample per side BootstrapDiffs computes
+// an interval for. Resamples of fewer values barely differ from the original,
+// so their interval collapses around the estimate and every difference would
+// look significant.
+const MinBootstrapSamples = 5
+
+// BootstrapDiffs estimates stat(b) - stat(a) for every statistic, each with a
+// percentile bootstrap confidence interval over the given number of resamples.
+// Every resample is shared by all statistics. With fewer than
+// MinBootstrapSamples values on either side the interval is unbounded, see
+// Interval.Bounded.
+func BootstrapDiffs(a, b []float64, statistics []Statistic, confidence float64, resamples int, rng *rand.Rand) []Interval {
+	sortedA := append([]float64(nil), a...)
+	sortedB := append([]float64(nil), b...)
+	sort.Float64s(sortedA)
+	sort.Float64s(sortedB)
+
+	intervals := make([]Interval, len(statistics))
+	if len(a) == 0 || len(b) == 0 {
+		return intervals
+	}
+
+	countsA := ones(len(sortedA))
+	countsB := ones(len(sortedB))
+	diffs := make([][]float64, len(statistics))
+	for i, stat := range statistics {
+		intervals[i].Estimate = stat(sortedB, countsB) - stat(sortedA, countsA)
+		diffs[i] = make([]float64, resamples)
+	}
+	if len(a) < MinBootstrapSamples || len(b) < MinBootstrapSamples {
+		for i := range intervals {
+			intervals[i].Low, intervals[i].High = math.Inf(-1), math.Inf(1)
+		}
+		return intervals
+	}
+
+	for r := 0; r < resamples; r++ {
+		resample(countsA, rng)
+		resample(countsB, rng)
+		for i, stat := range statistics {
+			diffs[i][r] = stat(sortedB, countsB) - stat(sortedA, countsA)
+		}
+	}
+
+	tail := (1 - confidence) / 2
+	for i := range intervals {
+		if resamples == 0 {
+			intervals[i].Low, intervals[i].High = intervals[i].Estimate, intervals[i].Estimate
+			continue
+		}
+		sort.Float64s(diffs[i])
+		intervals[i].Low = diffs[i][rankIndex(resamples, tail)]
+		intervals[i].High = diffs[i][rankIndex(resamples, 1-tail)]
+	}
+	return intervals
+}
+
+func ones(n int) []int {
+	counts := make([]int, n)
+	for i := range counts {
+		counts[i] = 1
+	}
+	return counts
+}
+
+// resample draws len(counts) values with replacement, recording in counts how
+// often each one was drawn.
+func resample(counts []int, rng *rand.Rand) {
+	for i := range counts {
+		counts[i] = 0
+	}
+	for range counts {
+		counts[rng.Intn(len(counts))]++
+	}
+}
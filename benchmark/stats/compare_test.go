@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func seq(from, to float64) []float64 {
+	var xs []float64
+	for v := from; v <= to; v++ {
+		xs = append(xs, v)
+	}
+	return xs
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		u, p float64
+	}{
+		{"shifted", seq(1, 10), seq(6, 15), 12.5, 0.0049},
+		{"reversed", seq(6, 15), seq(1, 10), 87.5, 0.0049},
+		{"identical", seq(1, 10), seq(1, 10), 50, 1},
+		{"all tied", []float64{3, 3, 3}, []float64{3, 3}, 3, 1},
+		{"empty", nil, seq(1, 10), 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p := MannWhitneyU(tt.a, tt.b)
+			if u != tt.u {
+				t.Errorf("U = %v, want %v", u, tt.u)
+			}
+			if math.Abs(p-tt.p) > 0.0005 {
+				t.Errorf("p = %v, want %v", p, tt.p)
+			}
+		})
+	}
+}
+
+func TestQuantileStat(t *testing.T) {
+	xs := seq(1, 10)
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0, 1},
+		{0.5, 5},
+		{0.9, 9},
+		{0.99, 10},
+		{1, 10},
+	}
+	for _, tt := range tests {
+		if got := Evaluate(QuantileStat(tt.q), xs); got != tt.want {
+			t.Errorf("quantile %v = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := Evaluate(MeanStat, xs); got != 5.5 {
+		t.Errorf("mean = %v, want 5.5", got)
+	}
+}
+
+func TestBootstrapDiffs(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        []float64
+		estimate    float64
+		bounded     bool
+		significant bool
+	}{
+		{"shifted", seq(1, 10), seq(6, 15), 5, true, true},
+		{"identical", seq(1, 10), seq(1, 10), 0, true, false},
+		{"single run", []float64{10}, []float64{12}, 2, false, false},
+		{"below minimum", seq(1, MinBootstrapSamples-1), seq(100, 100+MinBootstrapSamples-2), 99, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			diffs := BootstrapDiffs(tt.a, tt.b, []Statistic{MeanStat, QuantileStat(0.5)}, 0.95, 1000, rng)
+			diff := diffs[0]
+			if diff.Estimate != tt.estimate {
+				t.Errorf("estimate = %v, want %v", diff.Estimate, tt.estimate)
+			}
+			if diff.Bounded() != tt.bounded {
+				t.Errorf("bounded = %v, want %v (interval [%v, %v])", diff.Bounded(), tt.bounded, diff.Low, diff.High)
+			}
+			if diff.Bounded() && diff.Excludes(0) != tt.significant {
+				t.Errorf("interval [%v, %v] excludes 0 = %v, want %v", diff.Low, diff.High, diff.Excludes(0), tt.significant)
+			}
+			if diff.Bounded() && (diff.Low > diff.Estimate || diff.High < diff.Estimate) {
+				t.Errorf("estimate %v outside its interval [%v, %v]", diff.Estimate, diff.Low, diff.High)
+			}
+		})
+	}
+}
+
+func TestBootstrapDiffsDeterministic(t *testing.T) {
+	a, b := seq(1, 20), seq(3, 22)
+	first := BootstrapDiffs(a, b, []Statistic{MeanStat}, 0.95, 500, rand.New(rand.NewSource(7)))
+	second := BootstrapDiffs(a, b, []Statistic{MeanStat}, 0.95, 500, rand.New(rand.NewSource(7)))
+	if first[0] != second[0] {
+		t.Errorf("same seed gave %+v and %+v", first[0], second[0])
+	}
+}
@@ -0,0 +1,287 @@
+// Package stats keeps latency distributions in HDR histograms so percentiles
+// stay accurate for small runs and histograms can be merged across runs.
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// Latencies are recorded in microseconds between 1µs and one hour with
+	// three significant digits, i.e. at most 0.1% off.
+	lowestValue    = 1
+	highestValue   = int64(time.Hour / time.Microsecond)
+	significantDig = 3
+)
+
+// Histogram is a latency distribution.
+type Histogram struct {
+	h *hdrhistogram.Histogram
+}
+
+// NewHistogram returns an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{h: hdrhistogram.New(lowestValue, highestValue, significantDig)}
+}
+
+// Record adds d to the histogram. Durations outside the trackable range are
+// clamped to it.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / time.Microsecond)
+	if v < lowestValue {
+		v = lowestValue
+	}
+	if v > highestValue {
+		v = highestValue
+	}
+	_ = h.h.RecordValue(v)
+}
+
+// Merge adds every value recorded in other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other != nil {
+		h.h.Merge(other.h)
+	}
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() int64 {
+	return h.h.TotalCount()
+}
+
+// Percentile returns the value below which p percent of the recorded values fall.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	return micros(h.h.ValueAtPercentile(p))
+}
+
+// Summary returns the full set of statistics of the histogram.
+func (h *Histogram) Summary() Summary {
+	if h.Count() == 0 {
+		return Summary{}
+	}
+	return Summary{
+		Count:  h.Count(),
+		Min:    micros(h.h.Min()),
+		Max:    micros(h.h.Max()),
+		Mean:   time.Duration(h.h.Mean() * float64(time.Microsecond)).Round(time.Microsecond),
+		StdDev: time.Duration(h.h.StdDev() * float64(time.Microsecond)).Round(time.Microsecond),
+		P50:    h.Percentile(50),
+		P90:    h.Percentile(90),
+		P95:    h.Percentile(95),
+		P99:    h.Percentile(99),
+		P999:   h.Percentile(99.9),
+		P9999:  h.Percentile(99.99),
+	}
+}
+
+// MarshalJSON encodes the histogram in the compressed, base64 encoded
+// HdrHistogram V2 format, which other HdrHistogram implementations can read.
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	encoded, err := h.h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode histogram: %w", err)
+	}
+	return json.Marshal(string(encoded))
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := hdrhistogram.Decode([]byte(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to decode histogram: %w", err)
+	}
+	h.h = decoded
+	return nil
+}
+
+func micros(v int64) time.Duration {
+	return time.Duration(v) * time.Microsecond
+}
+
+// Summary is the set of statistics reported for a distribution.
+type Summary struct {
+	Count  int64         `json:"count"`
+	Min    time.Duration `json:"min_ns"`
+	Max    time.Duration `json:"max_ns"`
+	Mean   time.Duration `json:"mean_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+	P50    time.Duration `json:"p50_ns"`
+	P90    time.Duration `json:"p90_ns"`
+	P95    time.Duration `json:"p95_ns"`
+	P99    time.Duration `json:"p99_ns"`
+	P999   time.Duration `json:"p999_ns"`
+	P9999  time.Duration `json:"p9999_ns"`
+}
+
+// RunHistograms holds the distributions of one run: per record, per step
+// label, and per record path, input category and payload size bucket.
+// StepTotals is the exact time spent in every step label and Elapsed the wall
+// clock time of the run.
+type RunHistograms struct {
+	Run        int                      `json:"run"`
+	Isolation  string                   `json:"isolation"`
+	Elapsed    time.Duration            `json:"elapsed"`
+	Records    *Histogram               `json:"records"`
+	Steps      map[string]*Histogram    `json:"steps"`
+	StepTotals map[string]time.Duration `json:"step_totals"`
+	Paths      map[string]*Histogram    `json:"paths"`
+	Categories map[string]*Histogram    `json:"categories"`
+	Payloads   map[string]*Histogram    `json:"payloads,omitempty"`
+}
+
+// NewRunHistograms returns empty histograms for run.
+func NewRunHistograms(run int, isolation string) *RunHistograms {
+	return &RunHistograms{
+		Run:        run,
+		Isolation:  isolation,
+		Records:    NewHistogram(),
+		Steps:      map[string]*Histogram{},
+		StepTotals: map[string]time.Duration{},
+		Paths:      map[string]*Histogram{},
+		Categories: map[string]*Histogram{},
+		Payloads:   map[string]*Histogram{},
+	}
+}
+
+// RecordRecord adds the latency d of a record that took path and belongs to
+// category. An empty path or category is not broken down.
+func (r *RunHistograms) RecordRecord(d time.Duration, path, category string) {
+	r.Records.Record(d)
+	if path != "" {
+		histogramOf(r.Paths, path).Record(d)
+	}
+	if category != "" {
+		histogramOf(r.Categories, category).Record(d)
+	}
+}
+
+// RecordPayload adds the latency d of a record whose payload falls into
+// bucket.
+func (r *RunHistograms) RecordPayload(d time.Duration, bucket string) {
+	if r.Payloads == nil {
+		// Histograms stored before payload sizes were recorded.
+		r.Payloads = map[string]*Histogram{}
+	}
+	histogramOf(r.Payloads, bucket).Record(d)
+}
+
+// RecordStep adds d to the histogram of label.
+func (r *RunHistograms) RecordStep(label string, d time.Duration) {
+	histogramOf(r.Steps, label).Record(d)
+	r.StepTotals[label] += d
+}
+
+func histogramOf(histograms map[string]*Histogram, key string) *Histogram {
+	h, ok := histograms[key]
+	if !ok {
+		h = NewHistogram()
+		histograms[key] = h
+	}
+	return h
+}
+
+// Throughput returns the records processed per second of elapsed time.
+func (r *RunHistograms) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Records.Count()) / r.Elapsed.Seconds()
+}
+
+// Labels returns the step labels in alphabetical order.
+func (r *RunHistograms) Labels() []string {
+	return Keys(r.Steps)
+}
+
+// Keys returns the keys of histograms in alphabetical order.
+func Keys(histograms map[string]*Histogram) []string {
+	keys := make([]string, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge adds the distributions and elapsed time of other to r.
+func (r *RunHistograms) Merge(other *RunHistograms) {
+	r.Elapsed += other.Elapsed
+	r.Records.Merge(other.Records)
+	for _, m := range []struct{ into, from map[string]*Histogram }{
+		{r.Steps, other.Steps},
+		{r.Paths, other.Paths},
+		{r.Categories, other.Categories},
+		{r.Payloads, other.Payloads},
+	} {
+		for key, h := range m.from {
+			histogramOf(m.into, key).Merge(h)
+		}
+	}
+	for label, d := range other.StepTotals {
+		r.StepTotals[label] += d
+	}
+}
+
+// MergeRuns merges the histograms of several runs into one. Run and Isolation
+// of the result are left empty.
+func MergeRuns(runs []*RunHistograms) *RunHistograms {
+	merged := NewRunHistograms(0, "")
+	for _, run := range runs {
+		merged.Merge(run)
+	}
+	return merged
+}
+
+// AppendJSONL appends r as one line to the file at path.
+func AppendJSONL(path string, r *RunHistograms) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open histogram file: %w", err)
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write histograms: %w", err)
+	}
+	return nil
+}
+
+// ReadJSONL reads the runs written by AppendJSONL.
+func ReadJSONL(path string) ([]*RunHistograms, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []*RunHistograms
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		// Starting from empty histograms keeps files written before a field
+		// existed readable.
+		run := NewRunHistograms(0, "")
+		if err := json.Unmarshal(scanner.Bytes(), run); err != nil {
+			return runs, fmt.Errorf("line %d: %w", line, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
@@ -0,0 +1,150 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// StepStats is the latency distribution of one step label together with the
+// share of all step time it accounts for.
+type StepStats struct {
+	Label string
+	stats.Summary
+	Total time.Duration
+	Share float64
+}
+
+// StepBreakdown aggregates the steps recorded in h per label, the label that
+// took the most time in total first.
+func StepBreakdown(h *stats.RunHistograms) []StepStats {
+	var total time.Duration
+	for _, d := range h.StepTotals {
+		total += d
+	}
+
+	steps := make([]StepStats, 0, len(h.Steps))
+	for _, label := range h.Labels() {
+		step := StepStats{
+			Label:   label,
+			Summary: h.Steps[label].Summary(),
+			Total:   h.StepTotals[label],
+		}
+		if total > 0 {
+			step.Share = float64(step.Total) / float64(total)
+		}
+		steps = append(steps, step)
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Total > steps[j].Total })
+	return steps
+}
+
+// PrintStepBreakdown writes steps as a table.
+func PrintStepBreakdown(w io.Writer, steps []StepStats) {
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "🪜 Per-step latency:\n")
+	fmt.Fprintf(w, "  %-28s %8s %7s %12s %12s %12s %12s %12s\n", "step", "count", "share", "total", "p50", "p90", "p99", "max")
+	for _, s := range steps {
+		fmt.Fprintf(w, "  %-28s %8d %6.1f%% %12s %12s %12s %12s %12s\n",
+			s.Label, s.Count, s.Share*100, s.Total.Round(time.Microsecond), s.P50, s.P90, s.P99, s.Max)
+	}
+}
+
+// WriteCSVStepStats appends one row per step label to the CSV at path. run is
+// the run number, or "all" for steps aggregated over several runs.
+func WriteCSVStepStats(run string, isolation IsolationLevel, steps []StepStats, path string) error {
+	file, writeHeader, err := openCSVForAppend(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if writeHeader {
+		header := []string{"run", "isolation_level", "step_label", "count", "total_ms", "share", "mean_ms", "p50_ms", "p90_ms", "p99_ms", "max_ms"}
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.3f", d.Seconds()*1000) }
+	for _, s := range steps {
+		row := []string{
+			run,
+			string(isolation),
+			s.Label,
+			strconv.FormatInt(s.Count, 10),
+			ms(s.Total),
+			fmt.Sprintf("%.4f", s.Share),
+			ms(s.Mean),
+			ms(s.P50),
+			ms(s.P90),
+			ms(s.P99),
+			ms(s.Max),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVPathStats appends the latency distribution of every record path,
+// input category and payload size bucket in h to the CSV at path, one row each. run is the run number,
+// or "all" for runs merged into h.
+func WriteCSVPathStats(run string, isolation IsolationLevel, h *stats.RunHistograms, path string) error {
+	file, writeHeader, err := openCSVForAppend(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if writeHeader {
+		header := []string{"run", "isolation_level", "group", "name", "count", "mean_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms"}
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.3f", d.Seconds()*1000) }
+	for _, group := range []struct {
+		name       string
+		histograms map[string]*stats.Histogram
+		names      []string
+	}{
+		{"path", h.Paths, stats.Keys(h.Paths)},
+		{"category", h.Categories, stats.Keys(h.Categories)},
+		{"payload", h.Payloads, payloadBucketNames(h.Payloads)},
+	} {
+		for _, name := range group.names {
+			s := group.histograms[name].Summary()
+			row := []string{
+				run,
+				string(isolation),
+				group.name,
+				name,
+				strconv.FormatInt(s.Count, 10),
+				ms(s.Mean),
+				ms(s.P50),
+				ms(s.P90),
+				ms(s.P95),
+				ms(s.P99),
+				ms(s.Max),
+			}
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("failed to write row: %w", err)
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,269 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+	"gorm.io/gorm"
+)
+
+// TimeSeriesConfig decides when a window of the time series closes: every
+// Interval, every Records records, or both. The zero value records no time
+// series.
+type TimeSeriesConfig struct {
+	Interval time.Duration `json:"interval_ns,omitempty"`
+	Records  int           `json:"records,omitempty"`
+}
+
+func (c TimeSeriesConfig) enabled() bool {
+	return c.Interval > 0 || c.Records > 0
+}
+
+// Window holds the records that finished in one window of a run, together
+// with the table sizes when it closed.
+type Window struct {
+	Index int `json:"index"`
+	// Start and End are relative to the start of the run.
+	Start      time.Duration `json:"start_ns"`
+	End        time.Duration `json:"end_ns"`
+	Records    int           `json:"records"`
+	Failures   int           `json:"failures"`
+	Throughput float64       `json:"throughput"`
+	P50        time.Duration `json:"p50_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	Max        time.Duration `json:"max_ns"`
+	Tables     []TableSize   `json:"tables,omitempty"`
+}
+
+// TableSize is the size of one table. Rows and DeadRows are the statistics
+// collector's estimates and lag behind by up to a few hundred milliseconds.
+// Bytes includes indexes and ToastBytes, the out-of-line storage of large
+// values such as big payloads.
+type TableSize struct {
+	Name       string `json:"name"`
+	Rows       int64  `json:"rows"`
+	DeadRows   int64  `json:"dead_rows"`
+	Bytes      int64  `json:"bytes"`
+	ToastBytes int64  `json:"toast_bytes"`
+}
+
+// timeSeries cuts the records of a running run into windows. Workers report
+// finished records through observe; windows are completed, with table sizes
+// queried on a separate connection, and passed to emit by a single goroutine.
+// Workers never wait for that goroutine: the windows they cut queue up in
+// pending while table sizes are queried.
+type timeSeries struct {
+	cfg   TimeSeriesConfig
+	db    *gorm.DB
+	start time.Time
+	emit  func(Window) error
+
+	mu       sync.Mutex
+	current  *stats.Histogram
+	failures int
+	opened   time.Duration
+	index    int
+	pending  []Window
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	windows []Window
+	err     error
+	// tablesErr is the first failure to query table sizes. Windows without
+	// table sizes are still worth having, so it does not fail the run.
+	tablesErr error
+}
+
+func startTimeSeries(cfg TimeSeriesConfig, db *gorm.DB, start time.Time, emit func(Window) error) *timeSeries {
+	ts := &timeSeries{
+		cfg:     cfg,
+		db:      db,
+		start:   start,
+		emit:    emit,
+		current: stats.NewHistogram(),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go ts.loop()
+	return ts
+}
+
+// observe adds a finished record to the current window. It is a no-op on a
+// nil timeSeries so callers need not check whether one is recorded.
+func (ts *timeSeries) observe(rec RecordResult) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	ts.current.Record(rec.Duration)
+	if rec.Err != nil {
+		ts.failures++
+	}
+	if ts.cfg.Records > 0 && ts.current.Count() >= int64(ts.cfg.Records) {
+		ts.pending = append(ts.pending, ts.cut())
+		select {
+		case ts.wake <- struct{}{}:
+		default:
+			// The loop has been woken already and takes this window too.
+		}
+	}
+	ts.mu.Unlock()
+}
+
+// cut closes the current window and opens the next one. ts.mu must be held.
+func (ts *timeSeries) cut() Window {
+	now := time.Since(ts.start)
+	s := ts.current.Summary()
+	w := Window{
+		Index:    ts.index,
+		Start:    ts.opened,
+		End:      now,
+		Records:  int(s.Count),
+		Failures: ts.failures,
+		P50:      s.P50,
+		P99:      s.P99,
+		Max:      s.Max,
+	}
+	if elapsed := now - ts.opened; elapsed > 0 {
+		w.Throughput = float64(s.Count) / elapsed.Seconds()
+	}
+
+	ts.index++
+	ts.opened = now
+	ts.current = stats.NewHistogram()
+	ts.failures = 0
+	return w
+}
+
+func (ts *timeSeries) loop() {
+	defer close(ts.stopped)
+
+	var tick <-chan time.Time
+	if ts.cfg.Interval > 0 {
+		ticker := time.NewTicker(ts.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			ts.mu.Lock()
+			ts.pending = append(ts.pending, ts.cut())
+			ts.mu.Unlock()
+			ts.complete()
+		case <-ts.wake:
+			ts.complete()
+		case <-ts.done:
+			// Workers have finished, so nothing is observed any more.
+			ts.mu.Lock()
+			if ts.current.Count() > 0 {
+				ts.pending = append(ts.pending, ts.cut())
+			}
+			ts.mu.Unlock()
+			ts.complete()
+			return
+		}
+	}
+}
+
+// complete completes the pending windows. Table sizes are queried once for
+// all of them and kept on the last one; windows cut while the previous query
+// ran have none.
+func (ts *timeSeries) complete() {
+	ts.mu.Lock()
+	windows := ts.pending
+	ts.pending = nil
+	ts.mu.Unlock()
+	if len(windows) == 0 {
+		return
+	}
+
+	tables, err := queryTableSizes(ts.db)
+	if err != nil && ts.tablesErr == nil {
+		ts.tablesErr = err
+	}
+	windows[len(windows)-1].Tables = tables
+	for _, w := range windows {
+		ts.windows = append(ts.windows, w)
+		if err := ts.emit(w); err != nil && ts.err == nil {
+			ts.err = err
+		}
+	}
+}
+
+// stop closes the last window once all records have been observed and
+// returns every window of the run, with the first error met on the way.
+func (ts *timeSeries) stop() ([]Window, error) {
+	if ts == nil {
+		return nil, nil
+	}
+	close(ts.done)
+	<-ts.stopped
+	return ts.windows, ts.err
+}
+
+func queryTableSizes(db *gorm.DB) ([]TableSize, error) {
+	var tables []TableSize
+	err := db.Raw(`SELECT s.relname AS name, s.n_live_tup AS rows, s.n_dead_tup AS dead_rows,
+			pg_total_relation_size(s.relid) AS bytes,
+			CASE WHEN c.reltoastrelid = 0 THEN 0 ELSE pg_total_relation_size(c.reltoastrelid) END AS toast_bytes
+		FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid
+		ORDER BY s.relname`).Scan(&tables).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table sizes: %w", err)
+	}
+	return tables, nil
+}
+
+// WriteCSVWindows appends windows to the time-series CSV at path, one row per
+// window and table, so the series of every table can be plotted directly.
+func WriteCSVWindows(run int, isolation IsolationLevel, windows []Window, path string) error {
+	file, writeHeader, err := openCSVForAppend(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if writeHeader {
+		if err := writer.Write([]string{"run", "isolation_level", "window", "start_ms", "end_ms", "records", "failures", "throughput", "p50_ms", "p99_ms", "max_ms", "table", "live_rows", "dead_rows", "total_bytes", "toast_bytes"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+
+	for _, w := range windows {
+		row := []string{
+			strconv.Itoa(run),
+			string(isolation),
+			strconv.Itoa(w.Index),
+			fmt.Sprintf("%.3f", w.Start.Seconds()*1000),
+			fmt.Sprintf("%.3f", w.End.Seconds()*1000),
+			strconv.Itoa(w.Records),
+			strconv.Itoa(w.Failures),
+			fmt.Sprintf("%.2f", w.Throughput),
+			fmt.Sprintf("%.3f", w.P50.Seconds()*1000),
+			fmt.Sprintf("%.3f", w.P99.Seconds()*1000),
+			fmt.Sprintf("%.3f", w.Max.Seconds()*1000),
+		}
+		tables := w.Tables
+		if len(tables) == 0 {
+			tables = []TableSize{{}}
+		}
+		for _, t := range tables {
+			cells := append(row[:len(row):len(row)], t.Name, strconv.FormatInt(t.Rows, 10), strconv.FormatInt(t.DeadRows, 10),
+				strconv.FormatInt(t.Bytes, 10), strconv.FormatInt(t.ToastBytes, 10))
+			if err := writer.Write(cells); err != nil {
+				return fmt.Errorf("failed to write row: %w", err)
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
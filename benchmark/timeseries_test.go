@@ -0,0 +1,136 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// collectWindows starts a time series on a dry-run database and returns it
+// with the windows emitted so far.
+func collectWindows(t *testing.T, cfg TimeSeriesConfig) (*timeSeries, *[]Window) {
+	t.Helper()
+	db, _ := openTimedDB(t, true)
+	var emitted []Window
+	ts := startTimeSeries(cfg, db, time.Now(), func(w Window) error {
+		emitted = append(emitted, w)
+		return nil
+	})
+	return ts, &emitted
+}
+
+func observeN(ts *timeSeries, n int, failed bool) {
+	for i := 0; i < n; i++ {
+		rec := RecordResult{Index: i, Duration: time.Duration(i+1) * time.Millisecond}
+		if failed {
+			rec.Err = errors.New("failed")
+		}
+		ts.observe(rec)
+	}
+}
+
+// checkContiguous checks that windows are numbered in order and each starts
+// where the previous one ended.
+func checkContiguous(t *testing.T, windows []Window) {
+	t.Helper()
+	for i, w := range windows {
+		if w.Index != i {
+			t.Errorf("window %d has index %d", i, w.Index)
+		}
+		if i > 0 && w.Start != windows[i-1].End {
+			t.Errorf("window %d starts at %s, previous ended at %s", i, w.Start, windows[i-1].End)
+		}
+	}
+}
+
+func TestTimeSeriesCutsByCount(t *testing.T) {
+	ts, emitted := collectWindows(t, TimeSeriesConfig{Records: 3})
+	observeN(ts, 4, false)
+	observeN(ts, 3, true)
+	windows, err := ts.stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ records, failures int }{{3, 0}, {3, 2}, {1, 1}}
+	if len(windows) != len(want) {
+		t.Fatalf("got %d windows, want %d: %+v", len(windows), len(want), windows)
+	}
+	for i, w := range windows {
+		if w.Records != want[i].records || w.Failures != want[i].failures {
+			t.Errorf("window %d has %d records, %d failures; want %d, %d", i, w.Records, w.Failures, want[i].records, want[i].failures)
+		}
+	}
+	checkContiguous(t, windows)
+	if len(*emitted) != len(windows) {
+		t.Errorf("emitted %d windows, stop returned %d", len(*emitted), len(windows))
+	}
+}
+
+func TestTimeSeriesCutsByInterval(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	ts, _ := collectWindows(t, TimeSeriesConfig{Interval: interval})
+	observeN(ts, 5, false)
+	time.Sleep(3 * interval)
+	observeN(ts, 2, false)
+	windows, err := ts.stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windows) < 2 {
+		t.Fatalf("got %d windows over %s with a %s interval", len(windows), 3*interval, interval)
+	}
+	if windows[0].Records != 5 {
+		t.Errorf("first window has %d records, want the 5 observed before it closed", windows[0].Records)
+	}
+	// Windows closing while nothing finished are empty, so the records
+	// observed after the pause land in the last window that is not.
+	last := len(windows) - 1
+	for last > 0 && windows[last].Records == 0 {
+		last--
+	}
+	if last == 0 || windows[last].Records != 2 {
+		t.Errorf("last non-empty window %d has %d records, want the 2 observed after the pause", last, windows[last].Records)
+	}
+	total := 0
+	for _, w := range windows {
+		total += w.Records
+	}
+	if total != 7 {
+		t.Errorf("windows hold %d records, want 7", total)
+	}
+	checkContiguous(t, windows)
+}
+
+func TestTimeSeriesObserveDoesNotWait(t *testing.T) {
+	release := make(chan struct{})
+	db, _ := openTimedDB(t, true)
+	ts := startTimeSeries(TimeSeriesConfig{Records: 1}, db, time.Now(), func(Window) error {
+		<-release
+		return nil
+	})
+
+	// Every record closes a window while the loop is stuck emitting the
+	// first, as it would be on a slow table size query.
+	observed := make(chan struct{})
+	go func() {
+		observeN(ts, 100, false)
+		close(observed)
+	}()
+	select {
+	case <-observed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("observe waited for the windows to be completed")
+	}
+
+	close(release)
+	windows, err := ts.stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 100 {
+		t.Errorf("got %d windows, want 100", len(windows))
+	}
+	checkContiguous(t, windows)
+}
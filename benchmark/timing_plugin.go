@@ -0,0 +1,197 @@
+package benchmark
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	explainKey  struct{}
+	labelKey    struct{}
+	recorderKey struct{}
+)
+
+// WithExplain returns a context that makes the timing plugin capture an
+// EXPLAIN ANALYZE plan for every statement run with it.
+func WithExplain(ctx context.Context, explain bool) context.Context {
+	return context.WithValue(ctx, explainKey{}, explain)
+}
+
+// WithStepRecorder returns a context whose statements are recorded in rec.
+func WithStepRecorder(ctx context.Context, rec *StepRecorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+// WithStepLabel returns a context whose statements are recorded under label.
+func WithStepLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, labelKey{}, label)
+}
+
+// Label returns a session of tx whose statements are recorded under label.
+func Label(tx *gorm.DB, label string) *gorm.DB {
+	return tx.WithContext(WithStepLabel(tx.Statement.Context, label))
+}
+
+// StepRecorder collects the statements executed for one record.
+type StepRecorder struct {
+	mu    sync.Mutex
+	steps []StepTiming
+}
+
+func (r *StepRecorder) add(step StepTiming) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+// Steps returns the statements recorded so far, in execution order.
+func (r *StepRecorder) Steps() []StepTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]StepTiming(nil), r.steps...)
+}
+
+// TimingPlugin is a GORM plugin that times every Create, Query, Update,
+// Delete, Row and Raw statement run with a StepRecorder in its context. Each
+// statement is recorded with its rendered SQL, its vars and the label from
+// WithStepLabel. In explain mode it also captures the statement's
+// EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) plan right before executing it; the
+// time spent explaining is not part of the recorded duration.
+type TimingPlugin struct{}
+
+const (
+	timingStartKey   = "benchmark:timing_start"
+	timingExplainKey = "benchmark:timing_explain"
+)
+
+func (TimingPlugin) Name() string {
+	return "benchmark:timing"
+}
+
+func (p TimingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("benchmark:before_create", p.before),
+		cb.Create().After("gorm:create").Register("benchmark:after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register("benchmark:before_query", p.before),
+		cb.Query().After("gorm:query").Register("benchmark:after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register("benchmark:before_update", p.before),
+		cb.Update().After("gorm:update").Register("benchmark:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("benchmark:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("benchmark:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("benchmark:before_row", p.before),
+		cb.Row().After("gorm:row").Register("benchmark:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("benchmark:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("benchmark:after_raw", p.after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recorderFrom(db *gorm.DB) *StepRecorder {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	rec, _ := db.Statement.Context.Value(recorderKey{}).(*StepRecorder)
+	return rec
+}
+
+func (TimingPlugin) before(db *gorm.DB) {
+	if recorderFrom(db) == nil {
+		return
+	}
+
+	if explain, _ := db.Statement.Context.Value(explainKey{}).(bool); explain && !db.DryRun {
+		pool := &explainConnPool{ConnPool: db.Statement.ConnPool}
+		db.Statement.ConnPool = pool
+		db.InstanceSet(timingExplainKey, pool)
+	}
+	db.InstanceSet(timingStartKey, time.Now())
+}
+
+func (TimingPlugin) after(kind string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		rec := recorderFrom(db)
+		if rec == nil {
+			return
+		}
+		value, ok := db.InstanceGet(timingStartKey)
+		if !ok {
+			return
+		}
+		step := StepTiming{
+			Duration: time.Since(value.(time.Time)),
+			SQL:      db.Statement.SQL.String(),
+			Vars:     append([]interface{}(nil), db.Statement.Vars...),
+		}
+
+		step.Label, _ = db.Statement.Context.Value(labelKey{}).(string)
+		if step.Label == "" {
+			step.Label = fmt.Sprintf("%s_%s", kind, db.Statement.Table)
+		}
+
+		if value, ok := db.InstanceGet(timingExplainKey); ok {
+			pool := value.(*explainConnPool)
+			db.Statement.ConnPool = pool.ConnPool
+			step.Duration -= pool.elapsed
+			// Explain failures are kept on the step like any other output;
+			// printing them here would write to stdout mid-measurement.
+			if pool.err != nil {
+				step.Explain = fmt.Sprintf("❌ %v", pool.err)
+			} else if pool.plan != "" {
+				step.Explain = pool.plan
+				var err error
+				if step.Plan, err = ParseExplainPlan(pool.plan); err != nil {
+					step.Explain = fmt.Sprintf("❌ %v\n%s", err, pool.plan)
+				}
+			}
+		}
+
+		rec.add(step)
+	}
+}
+
+// explainConnPool explains the first statement sent through it before passing
+// it on to the wrapped connection.
+type explainConnPool struct {
+	gorm.ConnPool
+
+	explained bool
+	plan      string
+	err       error
+	elapsed   time.Duration
+}
+
+func (p *explainConnPool) explain(ctx context.Context, query string, args []interface{}) {
+	if p.explained {
+		return
+	}
+	p.explained = true
+
+	start := time.Now()
+	p.plan, p.err = explainOnPool(ctx, p.ConnPool, query, args)
+	p.elapsed = time.Since(start)
+}
+
+func (p *explainConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.explain(ctx, query, args)
+	return p.ConnPool.ExecContext(ctx, query, args...)
+}
+
+func (p *explainConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.explain(ctx, query, args)
+	return p.ConnPool.QueryContext(ctx, query, args...)
+}
+
+func (p *explainConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	p.explain(ctx, query, args)
+	return p.ConnPool.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,177 @@
+package benchmark
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// explainDelay is how long the fake database takes to explain a statement,
+// much longer than executing one.
+const explainDelay = 50 * time.Millisecond
+
+// fakeDriver is a database/sql driver that answers EXPLAIN with a plan after
+// explainDelay and accepts every other statement. It records the statements
+// it was sent.
+type fakeDriver struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+func (d *fakeDriver) sent() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.statements...)
+}
+
+type fakeConn struct{ driver *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakeConn) Commit() error                       { return nil }
+func (c *fakeConn) Rollback() error                     { return nil }
+
+func (c *fakeConn) record(query string) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.statements = append(c.driver.statements, query)
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	if strings.HasPrefix(query, "EXPLAIN") {
+		time.Sleep(explainDelay)
+		return &fakeRows{column: "QUERY PLAN", values: []string{`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "resources"}}]`}}, nil
+	}
+	return &fakeRows{column: "id"}, nil
+}
+
+type fakeRows struct {
+	column string
+	values []string
+}
+
+func (r *fakeRows) Columns() []string { return []string{r.column} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func openTimedDB(t *testing.T, dryRun bool) (*gorm.DB, *fakeDriver) {
+	t.Helper()
+	fake := &fakeDriver{}
+	sqlDB := sql.OpenDB(fakeConnector{fake})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DryRun:               dryRun,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(TimingPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, fake
+}
+
+type fakeConnector struct{ driver *fakeDriver }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.driver }
+
+func TestTimingPluginRecordsSteps(t *testing.T) {
+	db, _ := openTimedDB(t, true)
+	rec := &StepRecorder{}
+	tx := db.WithContext(WithStepRecorder(context.Background(), rec))
+
+	var rows []map[string]interface{}
+	Label(tx, "find_resource").Table("resources").Where("id = ?", 7).Find(&rows)
+	tx.Table("resources").Where("id = ?", 7).Update("tombstone", true)
+	tx.Exec("DELETE FROM resources WHERE id = ?", 7)
+	// Statements without a recorder are not timed.
+	db.Table("resources").Where("id = ?", 8).Find(&rows)
+
+	want := []StepTiming{
+		{Label: "find_resource", SQL: `SELECT * FROM "resources" WHERE id = $1`, Vars: []interface{}{7}},
+		{Label: "update_resources", SQL: `UPDATE "resources" SET "tombstone"=$1 WHERE id = $2`, Vars: []interface{}{true, 7}},
+		{Label: "raw_", SQL: `DELETE FROM resources WHERE id = $1`, Vars: []interface{}{7}},
+	}
+	steps := rec.Steps()
+	if len(steps) != len(want) {
+		t.Fatalf("recorded %d steps, want %d: %+v", len(steps), len(want), steps)
+	}
+	for i, step := range steps {
+		if step.Label != want[i].Label || step.SQL != want[i].SQL || !reflect.DeepEqual(step.Vars, want[i].Vars) {
+			t.Errorf("step %d = %s %q %v, want %s %q %v", i, step.Label, step.SQL, step.Vars, want[i].Label, want[i].SQL, want[i].Vars)
+		}
+		if step.Explain != "" || step.Plan != nil {
+			t.Errorf("step %d was explained without explain mode", i)
+		}
+	}
+}
+
+func TestTimingPluginSubtractsExplainTime(t *testing.T) {
+	db, fake := openTimedDB(t, false)
+	rec := &StepRecorder{}
+	ctx := WithExplain(WithStepRecorder(context.Background(), rec), true)
+
+	// Records run in a transaction, as in the runner, so the explain runs
+	// behind a savepoint.
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return Label(tx, "tombstone_resource").Table("resources").Where("id = ?", 7).Update("tombstone", true).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := rec.Steps()
+	if len(steps) != 1 {
+		t.Fatalf("recorded %d steps, want 1", len(steps))
+	}
+	step := steps[0]
+	if step.Plan == nil || step.Plan.Plan.NodeType != "Seq Scan" {
+		t.Fatalf("step plan = %+v, explain %q; want the Seq Scan plan", step.Plan, step.Explain)
+	}
+	if step.Duration >= explainDelay {
+		t.Errorf("step took %s, which includes the %s explain", step.Duration, explainDelay)
+	}
+
+	var explained, executed bool
+	for _, statement := range fake.sent() {
+		switch {
+		case strings.HasPrefix(statement, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) UPDATE"):
+			explained = true
+		case strings.HasPrefix(statement, "UPDATE"):
+			executed = explained
+		}
+	}
+	if !explained || !executed {
+		t.Errorf("statements %q, want the update explained and then executed", fake.sent())
+	}
+}
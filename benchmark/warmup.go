@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+// WarmupPolicy decides which records at the start of a run are treated as
+// warm-up and left out of its statistics. Warm-up records are still processed
+// and stored; every run starts on a freshly created database with cold
+// caches. The zero value measures every record.
+type WarmupPolicy struct {
+	// Records and Duration end the warm-up after that many records or that
+	// much time, whichever comes last.
+	Records  int           `json:"records"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// SteadyState extends the warm-up until the p50 of consecutive windows of
+	// Window records, in completion order, stays within Tolerance of their
+	// mean for SteadyWindows windows in a row.
+	SteadyState bool    `json:"steady_state"`
+	Window      int     `json:"window,omitempty"`
+	Tolerance   float64 `json:"tolerance,omitempty"`
+}
+
+// Defaults for steady-state detection.
+const (
+	DefaultSteadyWindow    = 200
+	DefaultSteadyTolerance = 0.05
+	SteadyWindows          = 3
+)
+
+// Warmup is the warm-up a policy found in one run.
+type Warmup struct {
+	// Records is the number of warm-up records and Elapsed the time from the
+	// start of the run until the last of them finished, which is where the
+	// measured window starts.
+	Records int           `json:"records"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// Steady reports whether steady-state detection ended the warm-up, and
+	// Unsteady whether it was asked for but never reached.
+	Steady   bool `json:"steady"`
+	Unsteady bool `json:"unsteady"`
+}
+
+// apply marks the warm-up records of records, which must have their Finished
+// offsets set.
+func (p WarmupPolicy) apply(records []RecordResult) Warmup {
+	order := make([]int, len(records))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return records[order[a]].Finished < records[order[b]].Finished
+	})
+
+	n := p.Records
+	if n > len(order) {
+		n = len(order)
+	}
+	for n < len(order) && records[order[n]].Finished <= p.Duration {
+		n++
+	}
+
+	var warmup Warmup
+	if p.SteadyState {
+		if start, ok := p.steadyStart(records, order[n:]); ok {
+			n += start
+			warmup.Steady = true
+		} else {
+			warmup.Unsteady = true
+		}
+	}
+
+	warmup.Records = n
+	for _, i := range order[:n] {
+		records[i].Warmup = true
+		if records[i].Finished > warmup.Elapsed {
+			warmup.Elapsed = records[i].Finished
+		}
+	}
+	return warmup
+}
+
+// steadyStart returns how many of the records in order precede the first of
+// SteadyWindows consecutive windows whose p50s agree within the tolerance.
+func (p WarmupPolicy) steadyStart(records []RecordResult, order []int) (int, bool) {
+	window := p.Window
+	if window <= 0 {
+		window = DefaultSteadyWindow
+	}
+	tolerance := p.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultSteadyTolerance
+	}
+
+	var medians []float64
+	for start := 0; start+window <= len(order); start += window {
+		h := stats.NewHistogram()
+		for _, i := range order[start : start+window] {
+			h.Record(records[i].Duration)
+		}
+		medians = append(medians, float64(h.Percentile(50)))
+
+		if len(medians) < SteadyWindows {
+			continue
+		}
+		last := medians[len(medians)-SteadyWindows:]
+		lo, hi, sum := last[0], last[0], 0.0
+		for _, m := range last {
+			lo, hi, sum = min(lo, m), max(hi, m), sum+m
+		}
+		mean := sum / float64(len(last))
+		if hi-mean <= tolerance*mean && mean-lo <= tolerance*mean {
+			return (len(medians) - SteadyWindows) * window, true
+		}
+	}
+	return 0, false
+}
+
+// String describes the warm-up and the measured window of a run that took
+// elapsed in total.
+func (w Warmup) String(measured int, elapsed time.Duration) string {
+	s := fmt.Sprintf("warm-up %d records in %s, measured %d records over %s", w.Records, w.Elapsed.Round(time.Millisecond), measured, (elapsed - w.Elapsed).Round(time.Millisecond))
+	switch {
+	case w.Steady:
+		s += " (steady state reached)"
+	case w.Unsteady:
+		s += " (⚠️ no steady state detected)"
+	}
+	return s
+}
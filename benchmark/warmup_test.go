@@ -0,0 +1,114 @@
+package benchmark
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// syntheticRun returns one record per latency, finishing a millisecond apart
+// in latency order but listed in a shuffled order, as concurrent workers
+// complete records out of input order.
+func syntheticRun(latencies []time.Duration) []RecordResult {
+	records := make([]RecordResult, len(latencies))
+	for i, d := range latencies {
+		records[i] = RecordResult{Index: i, Duration: d, Finished: time.Duration(i+1) * time.Millisecond}
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(records), func(i, j int) {
+		records[i], records[j] = records[j], records[i]
+	})
+	return records
+}
+
+// warming returns n latencies that fall from 100ms towards 10ms, followed by
+// steady ones jittering by 1% around 10ms.
+func warming(n, steady int) []time.Duration {
+	var latencies []time.Duration
+	for i := 0; i < n; i++ {
+		latencies = append(latencies, 100*time.Millisecond-time.Duration(i)*80*time.Millisecond/time.Duration(n))
+	}
+	for i := 0; i < steady; i++ {
+		latencies = append(latencies, 10*time.Millisecond+time.Duration(i%3-1)*100*time.Microsecond)
+	}
+	return latencies
+}
+
+func TestWarmupApply(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    WarmupPolicy
+		latencies []time.Duration
+		want      Warmup
+	}{
+		{
+			name:      "none",
+			latencies: warming(50, 50),
+			want:      Warmup{},
+		},
+		{
+			name:      "records",
+			policy:    WarmupPolicy{Records: 10},
+			latencies: warming(50, 50),
+			want:      Warmup{Records: 10, Elapsed: 10 * time.Millisecond},
+		},
+		{
+			name:      "duration outlasts records",
+			policy:    WarmupPolicy{Records: 10, Duration: 25 * time.Millisecond},
+			latencies: warming(50, 50),
+			want:      Warmup{Records: 25, Elapsed: 25 * time.Millisecond},
+		},
+		{
+			name:      "records outlast duration",
+			policy:    WarmupPolicy{Records: 30, Duration: 25 * time.Millisecond},
+			latencies: warming(50, 50),
+			want:      Warmup{Records: 30, Elapsed: 30 * time.Millisecond},
+		},
+		{
+			name:      "more records than the run",
+			policy:    WarmupPolicy{Records: 1000},
+			latencies: warming(50, 50),
+			want:      Warmup{Records: 100, Elapsed: 100 * time.Millisecond},
+		},
+		{
+			name:      "steady state",
+			policy:    WarmupPolicy{SteadyState: true, Window: 100},
+			latencies: warming(500, 500),
+			want:      Warmup{Records: 500, Elapsed: 500 * time.Millisecond, Steady: true},
+		},
+		{
+			// Windows start after the fixed warm-up. The one from 450 to 549
+			// is mostly steady, so its p50 already is.
+			name:      "steady state after a fixed warm-up",
+			policy:    WarmupPolicy{Records: 150, SteadyState: true, Window: 100},
+			latencies: warming(500, 500),
+			want:      Warmup{Records: 450, Elapsed: 450 * time.Millisecond, Steady: true},
+		},
+		{
+			name:      "never steady",
+			policy:    WarmupPolicy{SteadyState: true, Window: 100},
+			latencies: warming(1000, 0),
+			want:      Warmup{Unsteady: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := syntheticRun(tt.latencies)
+			got := tt.policy.apply(records)
+			if got != tt.want {
+				t.Fatalf("apply = %+v, want %+v", got, tt.want)
+			}
+			marked := 0
+			for _, rec := range records {
+				if rec.Warmup {
+					marked++
+					if rec.Finished > got.Elapsed {
+						t.Errorf("record %d finished at %s, after the warm-up ended at %s", rec.Index, rec.Finished, got.Elapsed)
+					}
+				}
+			}
+			if marked != got.Records {
+				t.Errorf("%d records marked as warm-up, want %d", marked, got.Records)
+			}
+		})
+	}
+}
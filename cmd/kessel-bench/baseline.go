@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+func baselineCommand(args []string) error {
+	if len(args) == 0 || (args[0] != "save" && args[0] != "check") {
+		return fmt.Errorf("usage: kessel-bench baseline save|check [flags]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("baseline "+action, flag.ExitOnError)
+	name := fs.String("name", "main", "name of the baseline")
+	option := fs.String("option", "option1", "schema option of the results")
+	tag := fs.String("tag", "", "tag of the results, as passed to run")
+	outDir := fs.String("out-dir", ".", "directory holding the results store and baselines")
+	storePath := fs.String("store", "", "results store to read (defaults to results.jsonl in -out-dir)")
+	sessionID := fs.String("session", "", "session to use: its ID, latest, or option:tag (defaults to the latest session of -option and -tag)")
+	thresholds := fs.String("thresholds", benchmark.DefaultThresholds, "check: comma-separated regression limits, e.g. p99=10%,throughput=5%,step.insert_resource.p50=20%,path.create.p99=15%")
+	_ = fs.Parse(args[1:])
+
+	if *sessionID == "" {
+		if *tag == "" {
+			return fmt.Errorf("-tag or -session is required")
+		}
+		*sessionID = *option + ":" + *tag
+	}
+	if *storePath == "" {
+		*storePath = filepath.Join(*outDir, "results.jsonl")
+	}
+	sessions, err := benchmark.ReadResultsStore(*storePath)
+	if err != nil {
+		return err
+	}
+	session, err := benchmark.FindSession(sessions, *sessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *storePath, err)
+	}
+	if err := session.Load(); err != nil {
+		return err
+	}
+
+	// Only the runs of this one session are compared, so earlier sessions of
+	// the same option and tag never leak into the baseline or the check.
+	var runs []*stats.RunHistograms
+	for _, run := range session.Runs {
+		runs = append(runs, run.Summary.Histograms)
+	}
+	current := benchmark.NewBaseline(*name, session.Option, session.Tag, runs)
+	path := baselineFile(*outDir, *name, session.Option, session.Tag)
+
+	if action == "save" {
+		if err := benchmark.SaveBaseline(current, path); err != nil {
+			return err
+		}
+		fmt.Printf("📌 Saved baseline %q from %d runs of session %s: %s\n", *name, len(runs), session.ID, path)
+		return nil
+	}
+
+	limits, err := benchmark.ParseThresholds(*thresholds)
+	if err != nil {
+		return err
+	}
+	return checkBaseline(path, current, limits)
+}
+
+// checkBaseline checks current against the baseline saved at path and fails
+// when any threshold is exceeded or could not be checked.
+func checkBaseline(path string, current *benchmark.Baseline, thresholds []benchmark.Threshold) error {
+	baseline, err := benchmark.LoadBaseline(path)
+	if err != nil {
+		return err
+	}
+	checks, err := benchmark.CheckBaseline(baseline, current, thresholds)
+	if err != nil {
+		return err
+	}
+	regressions, missing := benchmark.PrintBaselineChecks(os.Stdout, baseline, checks)
+	if regressions > 0 {
+		return fmt.Errorf("%d metrics regressed beyond their threshold against baseline %q", regressions, baseline.Name)
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d thresholds are not comparable against baseline %q: the level, step or path is missing on one side", missing, baseline.Name)
+	}
+	return nil
+}
+
+func baselineFile(dir, name, option, tag string) string {
+	return resultFile(dir, "baseline_"+name, option, tag, ".json")
+}
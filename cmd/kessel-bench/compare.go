@@ -0,0 +1,358 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+func compareCommand(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	outDir := fs.String("out-dir", ".", "directory holding the results store")
+	storePath := fs.String("store", "", "results store to read (defaults to results.jsonl in -out-dir)")
+	tag := fs.String("tag", "", "tag of the sessions to compare, unless given per side as option:tag or as a session")
+	confidence := fs.Float64("confidence", 0.95, "confidence level of the intervals; a difference is significant when its interval excludes zero")
+	resamples := fs.Int("resamples", 1000, "bootstrap resamples per comparison")
+	seed := fs.Int64("seed", 1, "seed of the bootstrap resampling")
+	reportPath := fs.String("report", "", "write the markdown report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: kessel-bench compare [flags] <session> <session>\n\n")
+		fmt.Fprintf(fs.Output(), "A session is a session ID, latest, or option[:tag] for the latest session of that option and tag.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two sessions, got %d", fs.NArg())
+	}
+	if *confidence <= 0 || *confidence >= 1 {
+		return fmt.Errorf("-confidence must be between 0 and 1, got %v", *confidence)
+	}
+	if *storePath == "" {
+		*storePath = filepath.Join(*outDir, "results.jsonl")
+	}
+
+	sessions, err := benchmark.ReadResultsStore(*storePath)
+	if err != nil {
+		return err
+	}
+	var sets [2]*resultSet
+	for i, arg := range fs.Args() {
+		session, err := benchmark.FindSession(sessions, arg)
+		if err != nil && !strings.Contains(arg, ":") && *tag != "" {
+			session, err = benchmark.FindSession(sessions, arg+":"+*tag)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", *storePath, err)
+		}
+		if err := session.Load(); err != nil {
+			return err
+		}
+		sets[i] = newResultSet(session)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *reportPath != "" {
+		file, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	c := comparison{
+		a:          sets[0],
+		b:          sets[1],
+		confidence: *confidence,
+		resamples:  *resamples,
+		rng:        rand.New(rand.NewSource(*seed)),
+	}
+	if err := c.writeMarkdown(out); err != nil {
+		return err
+	}
+	if *reportPath != "" {
+		fmt.Printf("📄 Comparison report: %s\n", *reportPath)
+	}
+	return nil
+}
+
+// resultSet holds the results of one session, grouped by isolation level:
+// the latencies of the measured records and one value per run for every
+// per-run metric.
+type resultSet struct {
+	name    string
+	session *benchmark.StoredSession
+	levels  []string
+	records map[string][]recordSample
+	runs    map[string]map[string][]float64
+}
+
+type recordSample struct {
+	ms   float64
+	path string
+}
+
+// runMetric is a per-run value compared between the sets, in the displayed
+// unit.
+type runMetric struct {
+	name           string
+	unit           string
+	higherIsBetter bool
+	value          func(benchmark.RunResult) float64
+}
+
+var runMetrics = []runMetric{
+	{"total time", "ms", false, func(r benchmark.RunResult) float64 { return ms(r.TotalElapsed) }},
+	{"throughput", "rec/s", true, func(r benchmark.RunResult) float64 { return r.Summary.Throughput }},
+	{"p50", "ms", false, func(r benchmark.RunResult) float64 { return ms(r.Summary.Latency.P50) }},
+	{"p90", "ms", false, func(r benchmark.RunResult) float64 { return ms(r.Summary.Latency.P90) }},
+	{"p99", "ms", false, func(r benchmark.RunResult) float64 { return ms(r.Summary.Latency.P99) }},
+	{"retries", "", false, func(r benchmark.RunResult) float64 { return float64(r.Summary.Retries) }},
+}
+
+func ms(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
+
+// newResultSet collects the runs of session. Warm-up records are left out.
+func newResultSet(session *benchmark.StoredSession) *resultSet {
+	set := &resultSet{
+		name:    session.Option + ":" + session.Tag,
+		session: session,
+		records: map[string][]recordSample{},
+		runs:    map[string]map[string][]float64{},
+	}
+	for _, run := range session.Runs {
+		level := string(run.Isolation)
+		if _, ok := set.runs[level]; !ok {
+			set.levels = append(set.levels, level)
+			set.runs[level] = map[string][]float64{}
+		}
+		for _, metric := range runMetrics {
+			set.runs[level][metric.name] = append(set.runs[level][metric.name], metric.value(run))
+		}
+		for _, rec := range run.MeasuredRecords() {
+			set.records[level] = append(set.records[level], recordSample{ms: ms(rec.Duration), path: string(rec.Path)})
+		}
+	}
+	return set
+}
+
+// comparison compares set b against set a; differences are b - a.
+type comparison struct {
+	a, b       *resultSet
+	confidence float64
+	resamples  int
+	rng        *rand.Rand
+}
+
+// comparisonRow is one compared statistic.
+type comparisonRow struct {
+	name       string
+	unit       string
+	nA, nB     int
+	a, b       float64
+	diff       stats.Interval
+	p          float64
+	lowerIsBad bool
+}
+
+func (c *comparison) writeMarkdown(w io.Writer) error {
+	fmt.Fprintf(w, "# %s vs %s\n\n", c.a.name, c.b.name)
+	for _, side := range []struct {
+		label string
+		set   *resultSet
+	}{{"A", c.a}, {"B", c.b}} {
+		session := side.set.session
+		fmt.Fprintf(w, "- **%s**: session `%s`, %s, %d runs, commit `%s`\n", side.label, session.ID, session.Started.Local().Format(time.DateTime), len(session.Runs), session.GitCommit)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Differences are B − A with %.0f%% percentile bootstrap confidence intervals (%d resamples). ", c.confidence*100, c.resamples)
+	fmt.Fprintf(w, "A difference is significant when its interval excludes zero; with fewer than %d values on either side no interval is computed. ", stats.MinBootstrapSamples)
+	fmt.Fprintf(w, "p is the two-sided Mann-Whitney U test of the whole distribution the row is taken from, n/a with fewer than %d values on either side.\n\n", stats.MinBootstrapSamples)
+	fmt.Fprintf(w, "Per-record rows pool the records of all runs at a level. Records of one run share its database state and are not independent, so their intervals are too narrow and their p values too small; prefer the per-run rows, which take each run as one value.\n")
+
+	compared := 0
+	for _, level := range c.a.levels {
+		if _, ok := c.b.runs[level]; !ok {
+			continue
+		}
+		compared++
+		fmt.Fprintf(w, "\n## %s\n", level)
+
+		fmt.Fprintf(w, "\n### Per-record latency\n\n")
+		writeRows(w, c.recordRows(level))
+
+		fmt.Fprintf(w, "\n### Per-run metrics\n\n")
+		writeRows(w, c.runRows(level))
+	}
+	if compared == 0 {
+		return fmt.Errorf("%s and %s have no isolation level in common", c.a.name, c.b.name)
+	}
+	return nil
+}
+
+func (c *comparison) recordRows(level string) []comparisonRow {
+	groups := []string{""}
+	paths := map[string]bool{}
+	for _, set := range []*resultSet{c.a, c.b} {
+		for _, sample := range set.records[level] {
+			if sample.path != "" {
+				paths[sample.path] = true
+			}
+		}
+	}
+	for path := range paths {
+		groups = append(groups, path)
+	}
+	sort.Strings(groups[1:])
+
+	var rows []comparisonRow
+	for _, group := range groups {
+		a := recordLatencies(c.a.records[level], group)
+		b := recordLatencies(c.b.records[level], group)
+		if len(a) == 0 || len(b) == 0 {
+			continue
+		}
+		suffix := ""
+		if group != "" {
+			suffix = " (" + group + ")"
+		}
+		rows = append(rows, c.compare(a, b, []namedStatistic{
+			{"mean" + suffix, stats.MeanStat},
+			{"p50" + suffix, stats.QuantileStat(0.50)},
+			{"p90" + suffix, stats.QuantileStat(0.90)},
+			{"p99" + suffix, stats.QuantileStat(0.99)},
+		}, "ms", false)...)
+	}
+	return rows
+}
+
+// recordLatencies returns the latencies of the records that took path, or of
+// all records for an empty path.
+func recordLatencies(samples []recordSample, path string) []float64 {
+	var ms []float64
+	for _, sample := range samples {
+		if path == "" || sample.path == path {
+			ms = append(ms, sample.ms)
+		}
+	}
+	return ms
+}
+
+func (c *comparison) runRows(level string) []comparisonRow {
+	var rows []comparisonRow
+	for _, metric := range runMetrics {
+		a, b := c.a.runs[level][metric.name], c.b.runs[level][metric.name]
+		if len(a) == 0 || len(b) == 0 {
+			continue
+		}
+		rows = append(rows, c.compare(a, b, []namedStatistic{{"mean " + metric.name, stats.MeanStat}}, metric.unit, metric.higherIsBetter)...)
+	}
+	return rows
+}
+
+type namedStatistic struct {
+	name string
+	stat stats.Statistic
+}
+
+func (c *comparison) compare(a, b []float64, statistics []namedStatistic, unit string, lowerIsBad bool) []comparisonRow {
+	fns := make([]stats.Statistic, len(statistics))
+	for i, s := range statistics {
+		fns[i] = s.stat
+	}
+	diffs := stats.BootstrapDiffs(a, b, fns, c.confidence, c.resamples, c.rng)
+	_, p := stats.MannWhitneyU(a, b)
+
+	rows := make([]comparisonRow, len(statistics))
+	for i, s := range statistics {
+		rows[i] = comparisonRow{
+			name:       s.name,
+			unit:       unit,
+			nA:         len(a),
+			nB:         len(b),
+			a:          stats.Evaluate(s.stat, a),
+			b:          stats.Evaluate(s.stat, b),
+			diff:       diffs[i],
+			p:          p,
+			lowerIsBad: lowerIsBad,
+		}
+	}
+	return rows
+}
+
+func writeRows(w io.Writer, rows []comparisonRow) {
+	if len(rows) == 0 {
+		fmt.Fprintf(w, "_No data on both sides._\n")
+		return
+	}
+	fmt.Fprintf(w, "| metric | n A | n B | A | B | B − A | CI | change | p | significant |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---:|---:|---|---:|---:|---|\n")
+	for _, r := range rows {
+		change := "n/a"
+		if r.a != 0 {
+			change = fmt.Sprintf("%+.1f%%", r.diff.Estimate/r.a*100)
+		}
+		ci := "n/a"
+		if r.diff.Bounded() {
+			ci = fmt.Sprintf("[%s, %s]", formatValue(r.diff.Low, ""), formatValue(r.diff.High, ""))
+		}
+		fmt.Fprintf(w, "| %s | %d | %d | %s | %s | %s | %s | %s | %s | %s |\n",
+			r.name, r.nA, r.nB,
+			formatValue(r.a, r.unit), formatValue(r.b, r.unit),
+			formatValue(r.diff.Estimate, r.unit), ci,
+			change, r.formatP(), r.verdict())
+	}
+}
+
+// verdict tells whether B is significantly better or worse than A. Lower is
+// better unless lowerIsBad is set.
+func (r comparisonRow) verdict() string {
+	if !r.diff.Bounded() {
+		return fmt.Sprintf("n/a, n < %d", stats.MinBootstrapSamples)
+	}
+	if !r.diff.Excludes(0) {
+		return "no"
+	}
+	if (r.diff.Estimate < 0) != r.lowerIsBad {
+		return "✅ yes, B better"
+	}
+	return "❌ yes, B worse"
+}
+
+func formatValue(v float64, unit string) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	if math.Abs(v) >= 1000 {
+		s = strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	if unit != "" {
+		s += " " + unit
+	}
+	return s
+}
+
+// formatP formats the p value of r, which is not given for too few values,
+// like the interval.
+func (r comparisonRow) formatP() string {
+	if r.nA < stats.MinBootstrapSamples || r.nB < stats.MinBootstrapSamples {
+		return "n/a"
+	}
+	if r.p < 0.001 {
+		return "<0.001"
+	}
+	return fmt.Sprintf("%.3f", r.p)
+}
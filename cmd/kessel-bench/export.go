@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	storePath := fs.String("store", "results.jsonl", "results store to read")
+	sessionID := fs.String("session", "latest", "session to export: its ID, latest, or option:tag for the latest of that option and tag")
+	list := fs.Bool("list", false, "list the sessions in the store instead of exporting one")
+	outDir := fs.String("out-dir", ".", "directory the CSVs are written to")
+	_ = fs.Parse(args)
+
+	sessions, err := benchmark.ReadResultsStore(*storePath)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, s := range sessions {
+			commit := s.GitCommit
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			if s.GitDirty {
+				commit += "+dirty"
+			}
+			fmt.Printf("%s  %s  %-8s %-20s %3d runs  %s  %s  PG %d\n", s.ID, s.Started.Local().Format(time.DateTime), s.Option, s.Tag, len(s.Runs), commit, s.Input, s.Environment.Postgres.VersionNumber)
+		}
+		return nil
+	}
+
+	session, err := benchmark.FindSession(sessions, *sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.Load(); err != nil {
+		return err
+	}
+	return exportSession(session, *outDir)
+}
+
+// exportSession writes the files a run with -csv would have written for
+// session, plus its manifest. It refuses to touch existing files since the
+// CSVs are appended to.
+func exportSession(session *benchmark.StoredSession, dir string) error {
+	file := func(kind, ext string) string {
+		return resultFile(dir, kind, session.Option, session.Tag, ext)
+	}
+	perRun := file("per_run_results", ".csv")
+	perRecord := file("per_record_results", ".csv")
+	stepStats := file("step_stats", ".csv")
+	pathStats := file("path_stats", ".csv")
+	histograms := file("histograms", ".jsonl")
+	manifest := file("manifest", ".json")
+	timeSeries := file("time_series", ".csv")
+	planChanges := ""
+	if session.Config.Explain {
+		planChanges = file("plan_changes", ".csv")
+	}
+
+	outputs := []string{manifest, perRun, perRecord, stepStats, pathStats, timeSeries, histograms, planChanges}
+	for _, path := range outputs {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	data, err := json.MarshalIndent(session.Session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifest, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	byLevel := map[benchmark.IsolationLevel][]benchmark.RunResult{}
+	var levels []benchmark.IsolationLevel
+	for i, result := range session.Runs {
+		if _, seen := byLevel[result.Isolation]; !seen {
+			levels = append(levels, result.Isolation)
+		}
+		byLevel[result.Isolation] = append(byLevel[result.Isolation], result)
+
+		run := strconv.Itoa(result.Run)
+		if err := benchmark.WriteCSVStoredRecords(session, i, perRecord); err != nil {
+			return err
+		}
+		if err := benchmark.WriteCSVForRun(result, perRun); err != nil {
+			return err
+		}
+		if err := benchmark.WriteCSVStepStats(run, result.Isolation, result.Summary.Steps, stepStats); err != nil {
+			return err
+		}
+		if err := benchmark.WriteCSVPathStats(run, result.Isolation, result.Summary.Histograms, pathStats); err != nil {
+			return err
+		}
+		if len(result.Windows) > 0 {
+			if err := benchmark.WriteCSVWindows(result.Run, result.Isolation, result.Windows, timeSeries); err != nil {
+				return err
+			}
+		}
+		if err := stats.AppendJSONL(histograms, result.Summary.Histograms); err != nil {
+			return err
+		}
+		if planChanges != "" {
+			if err := benchmark.WriteCSVPlanChanges(result.Run, result.Isolation, result.PlanChanges, planChanges); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, level := range levels {
+		if len(byLevel[level]) < 2 {
+			continue
+		}
+		runs := make([]*stats.RunHistograms, len(byLevel[level]))
+		for i, result := range byLevel[level] {
+			runs[i] = result.Summary.Histograms
+		}
+		merged := stats.MergeRuns(runs)
+		if err := benchmark.WriteCSVStepStats("all", level, benchmark.StepBreakdown(merged), stepStats); err != nil {
+			return err
+		}
+		if err := benchmark.WriteCSVPathStats("all", level, merged, pathStats); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("📤 Exported session %s (%d runs) to %s\n", session.ID, len(session.Runs), filepath.Clean(dir))
+	for _, path := range outputs {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/input_files"
+)
+
+func generateCommand(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	samples := fs.Int("n", 100, "number of records to generate")
+	dist := fs.String("dist", "zipf", "distribution of resource IDs: uniform, zipf, pareto, hotspot, sequential or latest")
+	modBase := fs.Uint64("mod-base", 100, "number of distinct resource IDs; Zipf values are folded modulo it")
+	zipfMax := fs.Uint64("zipf-max", 100, "zipf: largest value the Zipf generator can return, at least mod-base-1")
+	alpha := fs.Float64("alpha", 1.3, "zipf and latest: Zipf s parameter, must be > 1; pareto: shape")
+	xm := fs.Float64("xm", 1.0, "zipf: Zipf v parameter, must be >= 1; pareto: scale")
+	hotKeys := fs.Float64("hot-keys", 0.2, "hotspot: fraction of the IDs that are hot")
+	hotTraffic := fs.Float64("hot-traffic", 0.8, "hotspot: fraction of the records going to hot IDs")
+	start := fs.Uint64("start", 0, "sequential: first ID")
+	insert := fs.Float64("insert", 0.1, "latest: probability that a record creates a new resource")
+	workloadPath := fs.String("workload", "", "YAML or JSON workload spec declaring the record categories and payloads (default: the built-in workload)")
+	seed := fs.Int64("seed", 1, "seed for all randomness; the same seed and parameters always produce the same file")
+	output := fs.String("out", "", "output JSONL file")
+	_ = fs.Parse(args)
+
+	if *output == "" {
+		return fmt.Errorf("-out is required")
+	}
+
+	workload := input_files.DefaultWorkload()
+	if *workloadPath != "" {
+		w, err := input_files.LoadWorkload(*workloadPath)
+		if err != nil {
+			return fmt.Errorf("failed to load workload: %w", err)
+		}
+		workload = w
+	}
+
+	var distribution input_files.Distribution
+	var err error
+	switch *dist {
+	case "uniform":
+		distribution, err = input_files.NewUniform(*modBase)
+	case "zipf":
+		distribution, err = input_files.NewZipf(*modBase, *zipfMax, *alpha, *xm)
+	case "pareto":
+		distribution, err = input_files.NewPareto(*modBase, *alpha, *xm)
+	case "hotspot":
+		distribution, err = input_files.NewHotspot(*modBase, *hotKeys, *hotTraffic)
+	case "sequential":
+		distribution, err = input_files.NewSequential(*modBase, *start)
+	case "latest":
+		distribution, err = input_files.NewLatest(*modBase, *insert, *alpha)
+	default:
+		return fmt.Errorf("unknown distribution %q", *dist)
+	}
+	if err != nil {
+		return err
+	}
+
+	categories, err := input_files.GenerateRecords(workload, distribution, *samples, *seed, *output)
+	if err != nil {
+		return err
+	}
+
+	// Print summary
+	names := make([]string, 0, len(categories))
+	for cat := range categories {
+		names = append(names, cat)
+	}
+	sort.Strings(names)
+	for _, cat := range names {
+		fmt.Printf("%s: %d ids\n", cat, categories[cat])
+	}
+
+	sum, err := benchmark.FileSHA256(*output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🌱 Wrote %d %s distributed records of workload %s with seed %d to %s (sha256 %s)\n", *samples, distribution.Name(), workload.Name, *seed, *output, sum)
+	fmt.Printf("🏷️ Metadata: %s\n", input_files.MetadataPath(*output))
+	return nil
+}
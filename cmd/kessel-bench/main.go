@@ -0,0 +1,51 @@
+// Command kessel-bench drives the schema option benchmarks outside of go test.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"run", "run the benchmark for a schema option", runCommand},
+	{"generate", "generate an input file from a YAML workload with uniform, zipf, pareto, hotspot, sequential or latest IDs", generateCommand},
+	{"report", "summarize results files or render stored sessions as an HTML page", reportCommand},
+	{"baseline", "save results as a named baseline or check them against one", baselineCommand},
+	{"compare", "compare two sessions of the results store with confidence intervals", compareCommand},
+	{"export", "list the sessions in a results store or export one as CSV", exportCommand},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: kessel-bench <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'kessel-bench <command> -h' for the flags of a command.\n")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	"github.com/yourusername/go-db-bench/benchmark/report"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+)
+
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	htmlPath := fs.String("html", "", "render the sessions selected by -session from -store as a self-contained HTML page at this path")
+	storePath := fs.String("store", "results.jsonl", "results store to read with -html")
+	sessionIDs := fs.String("session", "latest", "comma-separated sessions to render with -html, latest or all")
+	_ = fs.Parse(args)
+
+	if *htmlPath != "" {
+		return renderHTML(*storePath, *sessionIDs, *htmlPath)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: kessel-bench report <per_run_results.csv|histograms.jsonl>... | -html report.html [-store results.jsonl] [-session latest]")
+	}
+	for _, path := range fs.Args() {
+		summarize := summarizePerRunCSV
+		if filepath.Ext(path) == ".jsonl" {
+			summarize = summarizeHistograms
+		}
+		if err := summarize(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// summarizePerRunCSV prints the runs of a file written by benchmark.WriteCSVForRun
+// together with their mean.
+func summarizePerRunCSV(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(rows) < 2 {
+		return fmt.Errorf("no runs recorded")
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	durationColumns := []string{"TotalTime ms", "P50ns", "P90ns", "P99ns", "MaxTime ns"}
+	for _, name := range append(durationColumns, "Run no", "RecordCount") {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("missing column %q", name)
+		}
+	}
+	isolation, hasIsolation := columns["IsolationLevel"]
+
+	fmt.Printf("\n📊 %s\n", path)
+	fmt.Printf("%-5s %-16s %10s %12s %12s %12s %12s %8s\n", "run", "isolation", "total", "p50", "p90", "p99", "max", "records")
+
+	var sums [5]time.Duration
+	for _, row := range rows[1:] {
+		var values [5]time.Duration
+		for i, name := range durationColumns {
+			n, err := strconv.ParseInt(row[columns[name]], 10, 64)
+			if err != nil {
+				return fmt.Errorf("bad %s %q: %w", name, row[columns[name]], err)
+			}
+			values[i] = time.Duration(n)
+			if i == 0 {
+				values[i] *= time.Millisecond
+			}
+			sums[i] += values[i]
+		}
+		level := "serializable"
+		if hasIsolation {
+			level = row[isolation]
+		}
+		fmt.Printf("%-5s %-16s %10s %12s %12s %12s %12s %8s\n", row[columns["Run no"]], level, values[0], values[1], values[2], values[3], values[4], row[columns["RecordCount"]])
+	}
+
+	n := time.Duration(len(rows) - 1)
+	fmt.Printf("%-5s %-16s %10s %12s %12s %12s %12s\n", "mean", "", sums[0]/n, sums[1]/n, sums[2]/n, sums[3]/n, sums[4]/n)
+	return nil
+}
+
+// summarizeHistograms prints the latency distributions of a file written by
+// benchmark.Runner, per isolation level with all its runs merged.
+func summarizeHistograms(path string) error {
+	runs, err := stats.ReadJSONL(path)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no runs recorded")
+	}
+
+	var levels []string
+	byLevel := map[string][]*stats.RunHistograms{}
+	for _, run := range runs {
+		if _, ok := byLevel[run.Isolation]; !ok {
+			levels = append(levels, run.Isolation)
+		}
+		byLevel[run.Isolation] = append(byLevel[run.Isolation], run)
+	}
+
+	fmt.Printf("\n📊 %s\n", path)
+	for _, level := range levels {
+		fmt.Printf("\n🔒 %s, %d runs\n", level, len(byLevel[level]))
+		benchmark.PrintLatency(os.Stdout, stats.MergeRuns(byLevel[level]))
+	}
+	return nil
+}
+
+// renderHTML writes the HTML report on the given sessions of the store.
+func renderHTML(storePath, ids, outPath string) error {
+	sessions, err := benchmark.ReadResultsStore(storePath)
+	if err != nil {
+		return err
+	}
+	selected := sessions
+	if ids != "all" {
+		selected = nil
+		for _, id := range strings.Split(ids, ",") {
+			session, err := benchmark.FindSession(sessions, strings.TrimSpace(id))
+			if err != nil {
+				return err
+			}
+			selected = append(selected, session)
+		}
+	}
+	for _, session := range selected {
+		if err := session.Load(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := report.Render(file, selected); err != nil {
+		return err
+	}
+	fmt.Printf("📈 Report on %d sessions written to %s\n", len(selected), outPath)
+	return file.Close()
+}
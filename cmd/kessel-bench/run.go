@@ -0,0 +1,232 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yourusername/go-db-bench/benchmark"
+	_ "github.com/yourusername/go-db-bench/benchmark/options"
+	"github.com/yourusername/go-db-bench/benchmark/stats"
+	"github.com/yourusername/go-db-bench/config"
+)
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	option := fs.String("option", "option1", "schema option to benchmark ("+strings.Join(benchmark.OptionNames(), ", ")+")")
+	input := fs.String("input", "benchmark/input_files/input_1000_records.jsonl", "input records file (JSONL)")
+	runs := fs.Int("runs", 1, "number of runs, each on a freshly recreated database")
+	concurrency := fs.Int("concurrency", 1, "number of workers processing records concurrently")
+	mode := fs.String("mode", string(benchmark.DispatchShared), "how records are spread over workers: shared or partitioned by resource")
+	maxRetries := fs.Int("max-retries", benchmark.DefaultRetryPolicy.MaxRetries, "retries of transactions failing with a serialization failure or deadlock (0 disables; never retried with isolation none)")
+	retryBackoff := fs.Duration("retry-backoff", benchmark.DefaultRetryPolicy.InitialBackoff, "backoff before the first retry, doubled for every further retry")
+	retryMaxBackoff := fs.Duration("retry-max-backoff", benchmark.DefaultRetryPolicy.MaxBackoff, "upper bound for the retry backoff")
+	isolation := fs.String("isolation", string(benchmark.IsolationSerializable), "comma-separated isolation levels to run one after another (none, read-committed, repeatable-read, serializable)")
+	warmupRecords := fs.Int("warmup-records", 0, "records at the start of every run left out of its statistics")
+	warmupDuration := fs.Duration("warmup", 0, "time at the start of every run whose records are left out of its statistics")
+	steadyState := fs.Bool("steady-state", false, "extend the warm-up until the rolling p50 stops drifting")
+	steadyWindow := fs.Int("steady-window", benchmark.DefaultSteadyWindow, "records per window for -steady-state")
+	steadyTolerance := fs.Float64("steady-tolerance", benchmark.DefaultSteadyTolerance, "allowed drift of the window p50s from their mean for -steady-state, as a fraction")
+	window := fs.Duration("window", 0, "record a time series with windows of this length during every run, polling the table sizes at the end of each (0 disables)")
+	windowRecords := fs.Int("window-records", 0, "record a time series, closing a window after this many records, alone or together with -window (0 disables)")
+	explain := fs.Bool("explain", false, "also record an EXPLAIN ANALYZE plan for every step; statements still run for real")
+	outDir := fs.String("out-dir", ".", "directory the results are written to")
+	storePath := fs.String("store", "", "results store the session is appended to (defaults to results.jsonl in -out-dir)")
+	writeCSV := fs.Bool("csv", false, "also write the per-run, per-record, step, path and time-series CSVs and the histograms JSONL; they can be exported from the store later")
+	tag := fs.String("tag", "", "suffix for the output file names (defaults to the input file name)")
+	baseline := fs.String("baseline", "", "check the results against this saved baseline and fail on regression")
+	thresholds := fs.String("thresholds", benchmark.DefaultThresholds, "regression limits for -baseline, see 'kessel-bench baseline check -h'")
+	saveBaseline := fs.String("save-baseline", "", "save the results as a baseline with this name")
+	_ = fs.Parse(args)
+
+	schemaOption, err := benchmark.LookupOption(*option)
+	if err != nil {
+		return err
+	}
+	dispatchMode, err := benchmark.ParseDispatchMode(*mode)
+	if err != nil {
+		return err
+	}
+	var levels []benchmark.IsolationLevel
+	for _, name := range strings.Split(*isolation, ",") {
+		level, err := benchmark.ParseIsolationLevel(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		levels = append(levels, level)
+	}
+	limits, err := benchmark.ParseThresholds(*thresholds)
+	if err != nil {
+		return err
+	}
+	if *warmupRecords < 0 || *warmupDuration < 0 {
+		return fmt.Errorf("-warmup-records and -warmup must not be negative")
+	}
+	if *runs < 1 {
+		return fmt.Errorf("-runs must be at least 1, got %d", *runs)
+	}
+	if *tag == "" {
+		*tag = strings.TrimSuffix(filepath.Base(*input), filepath.Ext(*input))
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	perRunCSVPath := resultFile(*outDir, "per_run_results", *option, *tag, ".csv")
+	perRecordCSVPath := resultFile(*outDir, "per_record_results", *option, *tag, ".csv")
+	planChangesCSVPath := resultFile(*outDir, "plan_changes", *option, *tag, ".csv")
+	stepStatsCSVPath := resultFile(*outDir, "step_stats", *option, *tag, ".csv")
+	pathStatsCSVPath := resultFile(*outDir, "path_stats", *option, *tag, ".csv")
+	histogramsPath := resultFile(*outDir, "histograms", *option, *tag, ".jsonl")
+	timeSeriesCSVPath := resultFile(*outDir, "time_series", *option, *tag, ".csv")
+
+	if *storePath == "" {
+		*storePath = filepath.Join(*outDir, "results.jsonl")
+	}
+	store, err := benchmark.OpenResultsStore(*storePath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	cfg := benchmark.RunnerConfig{
+		DB:          config.LoadDBConfig(),
+		Option:      schemaOption,
+		Explain:     *explain,
+		Concurrency: *concurrency,
+		Mode:        dispatchMode,
+		Retry: benchmark.RetryPolicy{
+			MaxRetries:     *maxRetries,
+			InitialBackoff: *retryBackoff,
+			MaxBackoff:     *retryMaxBackoff,
+		},
+		Warmup: benchmark.WarmupPolicy{
+			Records:  *warmupRecords,
+			Duration: *warmupDuration,
+		},
+		RunCount:  *runs,
+		InputPath: *input,
+		TimeSeries: benchmark.TimeSeriesConfig{
+			Interval: *window,
+			Records:  *windowRecords,
+		},
+		Store: store,
+	}
+	if *steadyState {
+		cfg.Warmup.SteadyState = true
+		cfg.Warmup.Window = *steadyWindow
+		cfg.Warmup.Tolerance = *steadyTolerance
+	}
+	if *writeCSV {
+		cfg.HistogramsPath = histogramsPath
+		if cfg.TimeSeries != (benchmark.TimeSeriesConfig{}) {
+			cfg.TimeSeriesCSVPath = timeSeriesCSVPath
+		}
+		cfg.PerRecordCSVPath = perRecordCSVPath
+		cfg.PerRunCSVPath = perRunCSVPath
+		cfg.StepStatsCSVPath = stepStatsCSVPath
+		cfg.PathStatsCSVPath = pathStatsCSVPath
+		if *explain {
+			cfg.PlanChangesCSVPath = planChangesCSVPath
+		}
+	}
+
+	session := benchmark.NewSession(cfg, *tag, levels)
+	if err := session.CaptureEnvironment(cfg.DB); err != nil {
+		return err
+	}
+	if err := store.StartSession(session); err != nil {
+		return err
+	}
+	cfg.SessionID = session.ID
+	benchmark.PrintManifest(os.Stdout, session)
+	fmt.Printf("🗃️ Recording to %s\n", *storePath)
+
+	// All isolation levels append to the same files; the isolation level
+	// column tells their rows apart.
+	var results []benchmark.RunResult
+	for _, level := range levels {
+		cfg.Isolation = level
+		levelResults, err := benchmark.NewRunner(cfg).Run()
+		results = append(results, levelResults...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", level, err)
+		}
+		if len(levelResults) > 1 {
+			merged := benchmark.AnalyzeRuns(os.Stdout, levelResults)
+			if err := writeMergedCSVs(cfg, level, merged); err != nil {
+				return fmt.Errorf("%s: %w", level, err)
+			}
+		}
+	}
+
+	fmt.Println()
+	for _, out := range []struct{ name, path string }{
+		{"Results store", *storePath},
+		{"Histograms", cfg.HistogramsPath},
+		{"Time series", cfg.TimeSeriesCSVPath},
+		{"Per-run results", cfg.PerRunCSVPath},
+		{"Per-record results", cfg.PerRecordCSVPath},
+		{"Step stats", cfg.StepStatsCSVPath},
+		{"Path stats", cfg.PathStatsCSVPath},
+		{"Plan changes", cfg.PlanChangesCSVPath},
+	} {
+		if out.path != "" {
+			fmt.Printf("📄 %s: %s\n", out.name, out.path)
+		}
+	}
+
+	var histograms []*stats.RunHistograms
+	for _, result := range results {
+		histograms = append(histograms, result.Summary.Histograms)
+	}
+	current := benchmark.NewBaseline(*saveBaseline, *option, *tag, histograms)
+	if *saveBaseline != "" {
+		path := baselineFile(*outDir, *saveBaseline, *option, *tag)
+		if err := benchmark.SaveBaseline(current, path); err != nil {
+			return err
+		}
+		fmt.Printf("📌 Baseline %q: %s\n", *saveBaseline, path)
+	}
+	var baselineErr error
+	if *baseline != "" {
+		baselineErr = checkBaseline(baselineFile(*outDir, *baseline, *option, *tag), current, limits)
+	}
+
+	failed := 0
+	for _, result := range results {
+		for _, failure := range result.Failures {
+			log.Printf("❌ run %d (%s): %v", result.Run, result.Isolation, failure)
+		}
+		failed += len(result.Failures)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d records failed", failed)
+	}
+	return baselineErr
+}
+
+// writeMergedCSVs appends the step and path statistics of all runs at level,
+// merged into one, to the CSVs configured in cfg.
+func writeMergedCSVs(cfg benchmark.RunnerConfig, level benchmark.IsolationLevel, merged *stats.RunHistograms) error {
+	if cfg.StepStatsCSVPath != "" {
+		if err := benchmark.WriteCSVStepStats("all", level, benchmark.StepBreakdown(merged), cfg.StepStatsCSVPath); err != nil {
+			return err
+		}
+	}
+	if cfg.PathStatsCSVPath != "" {
+		if err := benchmark.WriteCSVPathStats("all", level, merged, cfg.PathStatsCSVPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultFile is the path of the results file of the given kind written by a
+// run of option with the given tag.
+func resultFile(dir, kind, option, tag, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%s_%s%s", kind, option, tag, ext))
+}
@@ -66,7 +66,7 @@ func DropAndRecreateDatabase(cfg DBConfig) error {
 	if err == sql.ErrNoRows {
 		fmt.Println("✅ Database successfully deleted")
 	} else if err != nil {
-		log.Fatalf("error checking database: %v", err)
+		return fmt.Errorf("error checking database: %w", err)
 	} else {
 		fmt.Println("❌ Database still exists")
 	}
@@ -82,8 +82,16 @@ func DropAndRecreateDatabase(cfg DBConfig) error {
 }
 
 func ConnectDB() *gorm.DB {
-	cfg := LoadDBConfig()
+	db, err := OpenDB(LoadDBConfig())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return db
+}
 
+// OpenDB connects to the benchmark database described by cfg.
+func OpenDB(cfg DBConfig) (*gorm.DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName,
@@ -93,8 +101,8 @@ func ConnectDB() *gorm.DB {
 		Logger: logger.Default.LogMode(logger.Silent), // disable logging
 	})
 	if err != nil {
-		panic(fmt.Sprintf("failed to connect to database: %v", err))
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	return db
+	return db, nil
 }